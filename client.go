@@ -0,0 +1,30 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// Client bundles typed, dynamic and discovery clients of a single cluster
+type Client struct {
+	*kubernetes.Clientset
+	Dynamic dynamic.Interface
+	Mapper  meta.RESTMapper
+}
+
+func NewClient(restConfig *rest.Config) (client *Client, err error) {
+	client = &Client{}
+	if client.Clientset, err = kubernetes.NewForConfig(restConfig); err != nil {
+		return
+	}
+	if client.Dynamic, err = dynamic.NewForConfig(restConfig); err != nil {
+		return
+	}
+	discovery := memory.NewMemCacheClient(client.Clientset.Discovery())
+	client.Mapper = restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(discovery), discovery)
+	return
+}
@@ -1,50 +1,46 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
-)
-
-var (
-	ignoredNamespaces = []string{
-		"cattle-prometheus",
-		"cattle-system",
-		"kube-system",
-		"kube-public",
-		"kube-ingress",
-		"kube-storage",
-		"kube-node-lease",
-		"nginx-ingress",
-		"ingress-nginx",
-		"nfs-client-provisioner",
-		"security-scan",
-		"nfs-provisioner",
-		"autoops",
-		"istio-system",
-	}
+	"sync"
 )
 
 const (
-	nameAny      = "-"
-	nameWildcard = "*"
+	nameAny = "-"
+
+	namespaceCluster = "_cluster"
+	kindNamespace    = "namespace"
 
 	configDir    = ".koop"
 	configPrefix = "cluster-"
 	configSuffix = ".yaml"
 )
 
-func iterateCluster(cluster string, fn func(cluster string, client *kubernetes.Clientset) error) (err error) {
+// iterateCluster visits clusters matching pattern concurrently, see ParsePattern
+func iterateCluster(pool *Pool, cluster string, fn func(cluster string, client *Client) error) (err error) {
+	var pattern Pattern
+	if pattern, err = ParsePattern(cluster); err != nil {
+		return
+	}
 	var clusters []string
-	if cluster == nameAny {
+	if !pattern.IsLiteral() {
 		var home string
 		if home, err = os.UserHomeDir(); err != nil {
 			return
@@ -64,120 +60,260 @@ func iterateCluster(cluster string, fn func(cluster string, client *kubernetes.C
 			if !strings.HasSuffix(info.Name(), configSuffix) {
 				continue
 			}
-			clusters = append(clusters, strings.TrimSuffix(strings.TrimPrefix(info.Name(), configPrefix), configSuffix))
+			name := strings.TrimSuffix(strings.TrimPrefix(info.Name(), configPrefix), configSuffix)
+			if !pattern.Match(name) {
+				continue
+			}
+			clusters = append(clusters, name)
 		}
 	} else {
 		clusters = []string{cluster}
 	}
-	for _, cluster := range clusters {
+	return pool.Each(len(clusters), func(i int) (err error) {
+		cluster := clusters[i]
 		var home string
 		var restConfig *rest.Config
-		var client *kubernetes.Clientset
+		var client *Client
 		if home, err = os.UserHomeDir(); err != nil {
 			return
 		}
 		if restConfig, err = clientcmd.BuildConfigFromFlags("", filepath.Join(home, configDir, configPrefix+cluster+configSuffix)); err != nil {
 			return
 		}
-		if client, err = kubernetes.NewForConfig(restConfig); err != nil {
+		if client, err = NewClient(restConfig); err != nil {
 			return
 		}
-		if err = fn(cluster, client); err != nil {
-			return
-		}
-	}
-	return
+		return fn(cluster, client)
+	})
 }
 
-func iterateNamespace(ctx context.Context, client *kubernetes.Clientset, namespace string, fn func(namespace string) error) (err error) {
-	var namespaces []string
-	if namespace == nameAny || strings.HasSuffix(namespace, nameWildcard) {
-		var items *corev1.NamespaceList
-		if items, err = client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
+// iterateNamespace visits the cluster scope '_cluster' first if namespace is '-' or '_cluster', then namespaces matching pattern concurrently
+func iterateNamespace(pool *Pool, cluster string, client *Client, namespace string, fn func(namespace string) error) (err error) {
+	var pattern Pattern
+	if pattern, err = ParsePattern(namespace); err != nil {
+		return
+	}
+	if namespace == nameAny || namespace == namespaceCluster {
+		if err = fn(namespaceCluster); err != nil {
 			return
 		}
-	outerLoop:
-		for _, item := range items.Items {
-			for _, ignored := range ignoredNamespaces {
-				if strings.HasPrefix(strings.ToLower(item.Name), ignored) {
-					continue outerLoop
-				}
+		if namespace == namespaceCluster {
+			return
+		}
+	}
+	var namespaces []string
+	if err = pool.Run(func(ctx context.Context) (err error) {
+		if !pattern.IsLiteral() {
+			var items *corev1.NamespaceList
+			if items, err = client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
+				return
 			}
+			for _, item := range items.Items {
+				if koopConfig.IsNamespaceIgnored(cluster, item.Name) {
+					continue
+				}
 
-			if namespace == nameAny || strings.HasPrefix(item.Name, strings.TrimSuffix(namespace, nameWildcard)) {
-				namespaces = append(namespaces, item.Name)
+				if pattern.Match(item.Name) {
+					namespaces = append(namespaces, item.Name)
+				}
 			}
+		} else {
+			if _, err = client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+				return
+			}
+			namespaces = []string{namespace}
 		}
-	} else {
-		if _, err = client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		return
+	}); err != nil {
+		return
+	}
+	return pool.Each(len(namespaces), func(i int) error {
+		return fn(namespaces[i])
+	})
+}
+
+// resolveResources resolves a KIND argument, a comma separated list of kinds, '-' for all configured kinds and '!kind' to exclude a kind,
+// resources are sorted by apply order
+func resolveResources(client *Client, cluster string, kind string) (resources []*Resource, err error) {
+	var includes, excludes []string
+	for _, item := range strings.Split(kind, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.HasPrefix(item, "!") {
+			excludes = append(excludes, strings.TrimPrefix(item, "!"))
+		} else if item == nameAny {
+			includes = append(includes, koopConfig.KindsFor(cluster)...)
+		} else {
+			includes = append(includes, item)
+		}
+	}
+	if len(includes) == 0 {
+		includes = koopConfig.KindsFor(cluster)
+	}
+	excluded := map[string]bool{}
+	for _, item := range excludes {
+		var resource *Resource
+		if resource, err = findResource(client, item); err != nil {
 			return
 		}
-		namespaces = []string{namespace}
+		excluded[resource.Kind] = true
 	}
-	for _, namespace := range namespaces {
-		if err = fn(namespace); err != nil {
+	seen := map[string]bool{}
+	for _, item := range includes {
+		var resource *Resource
+		if resource, err = findResource(client, item); err != nil {
 			return
 		}
+		if excluded[resource.Kind] || seen[resource.Kind] {
+			continue
+		}
+		seen[resource.Kind] = true
+		resources = append(resources, resource)
 	}
+	sortResources(resources)
 	return
 }
 
-func iterateKind(kind string, fn func(kind string) error) (err error) {
-	var kinds []string
-	if kind == nameAny {
-		kinds = knownResourceNames
-	} else {
-		kinds = []string{kind}
+// iterateResource visits kinds one after another to keep the apply order, kinds not matching the scope of namespace are skipped
+func iterateResource(client *Client, cluster string, namespace string, kind string, fn func(resource *Resource) error) (err error) {
+	var resources []*Resource
+	if resources, err = resolveResources(client, cluster, kind); err != nil {
+		return
 	}
-	for _, kind := range kinds {
-		if err = fn(kind); err != nil {
+	for _, resource := range resources {
+		if resource.Namespaced == (namespace == namespaceCluster) {
+			continue
+		}
+		if err = fn(resource); err != nil {
 			return
 		}
 	}
 	return
 }
 
-func commandPush(ctx context.Context, cluster string, namespace string, kind string, name string) (err error) {
-	if err = iterateCluster(cluster, func(cluster string, client *kubernetes.Clientset) error {
-		return iterateNamespace(ctx, client, namespace, func(namespace string) error {
-			return iterateKind(kind, func(kind string) (err error) {
-				var resource *Resource
-				if resource, err = findResource(kind); err != nil {
-					return
-				}
+func listLocalNames(dir string) (names []string, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			log.Println("found unexpected directory in:", dir)
+			continue
+		}
+		if !strings.HasSuffix(info.Name(), ".yaml") {
+			log.Println("found unexpected file", info.Name(), "in:", dir, ", for compatible reasons, all YAML files must has extension '.yaml', NOT '.yml'")
+			continue
+		}
+		names = append(names, strings.TrimSuffix(info.Name(), ".yaml"))
+	}
+	return
+}
+
+func commandPush(ctx context.Context, opts PushOptions, filter *Filter, cluster string, namespace string, kind string, name string) (err error) {
+	if err = opts.Validate(); err != nil {
+		return
+	}
+	if err = filter.Compile(name); err != nil {
+		return
+	}
+	label := "PUSH"
+	if opts.DryRun != DryRunNone {
+		label = "PUSH (" + opts.DryRun + " dry run)"
+	}
+	if opts.Prune && filter.IsLiteral() {
+		err = errors.New("prune requires NAME to be '-' or a pattern")
+		return
+	}
+	pool := NewPool(ctx, opts.Concurrency)
+	mu := &sync.Mutex{}
+	var rejected int
+	var candidates []pruneCandidate
+	var targets []rolloutTarget
+	if err = iterateCluster(pool, cluster, func(cluster string, client *Client) error {
+		return iterateNamespace(pool, cluster, client, namespace, func(namespace string) error {
+			return iterateResource(client, cluster, namespace, kind, func(resource *Resource) (err error) {
+				kind := resource.Kind
 				dir := filepath.Join(cluster, namespace, kind)
 				var names []string
-				if name == nameAny {
-					var infos []os.FileInfo
-					if infos, err = ioutil.ReadDir(dir); err != nil {
-						if os.IsNotExist(err) {
-							err = nil
-						}
+				if filter.IsLiteral() {
+					names = []string{name}
+				} else {
+					if names, err = listLocalNames(dir); err != nil {
 						return
 					}
-					for _, info := range infos {
-						if info.IsDir() {
-							log.Println("found unexpected directory in:", dir)
-							continue
+					names = filter.Names(names)
+				}
+				// list once to compare with, in place of getting each object
+				var live map[string][]byte
+				if !filter.IsLiteral() && len(names) > 0 {
+					if err = pool.Run(func(ctx context.Context) (err error) {
+						var objects []Object
+						if objects, err = resource.List(ctx, client, namespace, metav1.ListOptions{}); err != nil {
+							return
 						}
-						if !strings.HasSuffix(info.Name(), ".yaml") {
-							log.Println("found unexpected file", info.Name(), "in:", dir, ", for compatible reasons, all YAML files must has extension '.yaml', NOT '.yml'")
-							continue
+						live = map[string][]byte{}
+						for _, object := range objects {
+							live[object.Name] = object.JSON
 						}
-						names = append(names, strings.TrimSuffix(info.Name(), ".yaml"))
+						return
+					}); err != nil {
+						return
 					}
-				} else {
-					names = []string{name}
 				}
-				for _, name := range names {
-					log.Printf("PUSH: %s/%s/%s/%s", cluster, namespace, kind, name)
+				if err = pool.Each(len(names), func(i int) (err error) {
+					name := names[i]
 					var buf []byte
 					if buf, err = ioutil.ReadFile(filepath.Join(dir, name+".yaml")); err != nil {
 						return
 					}
-					if err = resource.SetCanonicalYAML(ctx, client, namespace, name, buf); err != nil {
+					var selected bool
+					if selected, err = filter.MatchYAML(buf, namespace, name); err != nil || !selected {
 						return
 					}
+					return pool.Run(func(ctx context.Context) (err error) {
+						var result string
+						if result, err = resource.SetCanonicalYAML(ctx, client, cluster, namespace, name, buf, live, opts); err != nil {
+							if result != ResultRejected {
+								result = ResultFailed
+							}
+							log.Printf("%s: %s/%s/%s/%s: %s: %s", label, cluster, namespace, kind, name, result, err.Error())
+							// keep rehearsing the remaining objects in dry run mode
+							if result == ResultRejected && opts.DryRun != DryRunNone {
+								mu.Lock()
+								rejected++
+								mu.Unlock()
+								err = nil
+							}
+							return
+						}
+						log.Printf("%s: %s/%s/%s/%s: %s", label, cluster, namespace, kind, name, result)
+						if opts.Wait && opts.DryRun == DryRunNone && resource.Rollout != nil && result != ResultSkipped {
+							mu.Lock()
+							targets = append(targets, rolloutTarget{client: client, resource: resource, cluster: cluster, namespace: namespace, name: name})
+							mu.Unlock()
+						}
+						return
+					})
+				}); err != nil {
+					return
+				}
+				if opts.Prune {
+					return pool.Run(func(ctx context.Context) (err error) {
+						var found []pruneCandidate
+						if found, err = collectPruneCandidates(ctx, client, resource, filter, cluster, namespace, dir, names); err != nil {
+							return
+						}
+						mu.Lock()
+						candidates = append(candidates, found...)
+						mu.Unlock()
+						return
+					})
 				}
 				return
 			})
@@ -185,53 +321,415 @@ func commandPush(ctx context.Context, cluster string, namespace string, kind str
 	}); err != nil {
 		return
 	}
+	// namespaces are visited concurrently, restore the visiting order for prune and rollout
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].less(candidates[j])
+	})
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].less(targets[j])
+	})
+	filter.LogSkipped()
+	if err = executePrune(ctx, opts, label, candidates); err != nil {
+		return
+	}
+	var failed int
+	for _, target := range targets {
+		if err = waitRollout(ctx, target, opts.WaitTimeout); err != nil {
+			log.Printf("ROLLOUT: %s", err.Error())
+			failed++
+			err = nil
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d workloads failed to roll out", failed)
+		return
+	}
+	if rejected > 0 {
+		err = fmt.Errorf("%d objects rejected", rejected)
+		return
+	}
 	return
 }
 
-func commandPull(ctx context.Context, cluster string, namespace string, kind string, name string) (err error) {
-	if err = iterateCluster(cluster, func(cluster string, client *kubernetes.Clientset) error {
-		return iterateNamespace(ctx, client, namespace, func(namespace string) error {
-			return iterateKind(kind, func(kind string) (err error) {
-				var resource *Resource
-				if resource, err = findResource(kind); err != nil {
-					return
-				}
+func commandPull(ctx context.Context, concurrency int, minimal bool, headers bool, filter *Filter, cluster string, namespace string, kind string, name string) (err error) {
+	if err = filter.Compile(name); err != nil {
+		return
+	}
+	pool := NewPool(ctx, concurrency)
+	if err = iterateCluster(pool, cluster, func(cluster string, client *Client) error {
+		return iterateNamespace(pool, cluster, client, namespace, func(namespace string) error {
+			return iterateResource(client, cluster, namespace, kind, func(resource *Resource) (err error) {
+				kind := resource.Kind
 
 				dir := filepath.Join(cluster, namespace, kind)
 
-				var names []string
-				if name == nameAny {
-					_ = os.RemoveAll(dir)
-					log.Printf("CLEAN: %s/%s/%s", cluster, namespace, kind)
-					if names, err = resource.List(ctx, client, namespace); err != nil {
+				var objects []Object
+				var managed []string
+				if err = pool.Run(func(ctx context.Context) (err error) {
+					if !filter.IsLiteral() {
+						if objects, err = resource.List(ctx, client, namespace, filter.ListOptions()); err != nil {
+							return
+						}
+						objects, managed = filter.Split(cluster, resource, objects)
 						return
 					}
-				} else {
-					names = []string{name}
+					var data []byte
+					if data, err = resource.GetJSON(ctx, client, namespace, name); err != nil {
+						return
+					}
+					var selected bool
+					if selected, err = filter.MatchJSON(data, namespace, name); err != nil || !selected {
+						return
+					}
+					objects = []Object{{Name: name, JSON: data}}
+					return
+				}); err != nil {
+					return
+				}
+
+				if !filter.IsLiteral() {
+					var names []string
+					if names, err = listLocalNames(dir); err != nil {
+						return
+					}
+					pulled := map[string]bool{}
+					for _, object := range objects {
+						pulled[object.Name] = true
+					}
+					// files of skipped managed objects are kept as well
+					for _, name := range managed {
+						pulled[name] = true
+					}
+					// excluded, unmatched and unselected files are kept, pulled ones are merged below
+					for _, name := range filter.Names(names) {
+						if pulled[name] {
+							continue
+						}
+						path := filepath.Join(dir, name+".yaml")
+						if filter.HasSelectors() {
+							var buf []byte
+							if buf, err = ioutil.ReadFile(path); err != nil {
+								return
+							}
+							var selected bool
+							if selected, err = filter.MatchYAML(buf, namespace, name); err != nil {
+								return
+							}
+							if !selected {
+								continue
+							}
+						}
+						_ = os.Remove(path)
+					}
+					log.Printf("CLEAN: %s/%s/%s", cluster, namespace, kind)
 				}
 
 				if err = os.MkdirAll(dir, 0755); err != nil {
 					return
 				}
 
-				for _, name := range names {
-					log.Printf("PULL: %s/%s/%s/%s", cluster, namespace, kind, name)
+				mode := os.FileMode(0644)
+				if resource.Encrypted {
+					mode = 0600
+				}
+
+				return pool.Each(len(objects), func(i int) (err error) {
+					object := objects[i]
 					var buf []byte
-					if buf, err = resource.GetCanonicalYAML(ctx, client, namespace, name); err != nil {
+					if buf, err = resource.CanonicalYAML(cluster, namespace, object.Name, object.JSON, minimal, headers); err != nil {
 						return
 					}
+					path := filepath.Join(dir, object.Name+".yaml")
 					if len(buf) == 0 {
-						continue
+						_ = os.Remove(path)
+						return
+					}
+					// keep comments and key order of the existing file
+					if existing, err := ioutil.ReadFile(path); err == nil {
+						if buf, err = MergeYAML(existing, buf); err != nil {
+							return err
+						}
+					}
+					if err = ioutil.WriteFile(path, buf, mode); err != nil {
+						return
+					}
+					log.Printf("PULL: %s/%s/%s/%s", cluster, namespace, kind, object.Name)
+					return
+				})
+			})
+		})
+	}); err != nil {
+		return
+	}
+	filter.LogSkipped()
+	return
+}
+
+func commandDiff(ctx context.Context, concurrency int, filter *Filter, cluster string, namespace string, kind string, name string) (err error) {
+	if err = filter.Compile(name); err != nil {
+		return
+	}
+	color := IsColorTerminal()
+	pool := NewPool(ctx, concurrency)
+	mu := &sync.Mutex{}
+	var drifted int
+	if err = iterateCluster(pool, cluster, func(cluster string, client *Client) error {
+		return iterateNamespace(pool, cluster, client, namespace, func(namespace string) error {
+			return iterateResource(client, cluster, namespace, kind, func(resource *Resource) (err error) {
+				kind := resource.Kind
+
+				dir := filepath.Join(cluster, namespace, kind)
+
+				remotes := map[string][]byte{}
+				managed := map[string]bool{}
+				if err = pool.Run(func(ctx context.Context) (err error) {
+					if !filter.IsLiteral() {
+						var objects []Object
+						if objects, err = resource.List(ctx, client, namespace, filter.ListOptions()); err != nil {
+							return
+						}
+						var skipped []string
+						objects, skipped = filter.Split(cluster, resource, objects)
+						for _, object := range objects {
+							remotes[object.Name] = object.JSON
+						}
+						for _, name := range skipped {
+							managed[name] = true
+						}
+						return
 					}
-					if err = ioutil.WriteFile(filepath.Join(dir, name+".yaml"), buf, 0755); err != nil {
+					var data []byte
+					if data, err = resource.GetJSON(ctx, client, namespace, name); err != nil {
+						if apierrors.IsNotFound(err) {
+							err = nil
+						}
+						return
+					}
+					var selected bool
+					if selected, err = filter.MatchJSON(data, namespace, name); err != nil || !selected {
 						return
 					}
+					remotes[name] = data
+					return
+				}); err != nil {
+					return
 				}
-				return
+
+				var names []string
+				if !filter.IsLiteral() {
+					var localNames, remoteNames []string
+					if localNames, err = listLocalNames(dir); err != nil {
+						return
+					}
+					var matched []string
+					// local files of skipped managed objects are not compared
+					for _, localName := range filter.Names(localNames) {
+						if !managed[localName] {
+							matched = append(matched, localName)
+						}
+					}
+					for remoteName := range remotes {
+						remoteNames = append(remoteNames, remoteName)
+					}
+					names = mergeNames(matched, remoteNames)
+				} else {
+					names = []string{name}
+				}
+
+				return pool.Each(len(names), func(i int) (err error) {
+					name := names[i]
+					path := filepath.Join(dir, name+".yaml")
+
+					var local []byte
+					if local, err = ioutil.ReadFile(path); err != nil {
+						if os.IsNotExist(err) {
+							err = nil
+						} else {
+							return
+						}
+					} else {
+						// a local file not selected is compared only with a selected live object
+						if _, ok := remotes[name]; !ok {
+							var selected bool
+							if selected, err = filter.MatchYAML(local, namespace, name); err != nil || !selected {
+								return
+							}
+						}
+						// ordered as the live object, so that only values differ
+						var gvk schema.GroupVersionKind
+						if data, ok := remotes[name]; ok {
+							gvk = resource.typeOf(data)
+						}
+						if local, err = resource.NormalizeYAML(client, cluster, namespace, name, local, true, gvk); err != nil {
+							return
+						}
+					}
+
+					var remote []byte
+					if data, ok := remotes[name]; ok {
+						if remote, err = resource.CanonicalYAML(cluster, namespace, name, data, true, false); err != nil {
+							return
+						}
+						// resource chose to skip this object
+						if len(remote) == 0 {
+							return
+						}
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					if local == nil && remote == nil {
+						return
+					}
+					if local == nil {
+						drifted++
+						log.Printf("ONLY CLUSTER: %s/%s/%s/%s", cluster, namespace, kind, name)
+						return
+					}
+					if remote == nil {
+						drifted++
+						log.Printf("ONLY LOCAL: %s/%s/%s/%s", cluster, namespace, kind, name)
+						return
+					}
+					if out := UnifiedDiff("live/"+path, "local/"+path, remote, local, color); out != "" {
+						drifted++
+						log.Printf("DRIFT: %s/%s/%s/%s", cluster, namespace, kind, name)
+						fmt.Print(out)
+					}
+					return
+				})
 			})
 		})
 	}); err != nil {
 		return
 	}
+	filter.LogSkipped()
+	if drifted > 0 {
+		err = fmt.Errorf("found %d drifted objects", drifted)
+		return
+	}
 	return
 }
+
+func mergeNames(a []string, b []string) (names []string) {
+	seen := map[string]bool{}
+	for _, list := range [][]string{a, b} {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func secretFile(cluster string, namespace string, name string) string {
+	return filepath.Join(cluster, namespace, "secret", name+".yaml")
+}
+
+func commandSecretReveal(cluster string, namespace string, name string) (err error) {
+	var buf []byte
+	if buf, err = ioutil.ReadFile(secretFile(cluster, namespace, name)); err != nil {
+		return
+	}
+	if buf, err = YAML2JSON(buf); err != nil {
+		return
+	}
+	if buf, err = RevealSecretJSON(buf, namespace, name); err != nil {
+		return
+	}
+	if buf, err = JSON2YAML(buf); err != nil {
+		return
+	}
+	_, err = os.Stdout.Write(buf)
+	return
+}
+
+func commandSecretEdit(cluster string, namespace string, name string) (err error) {
+	file := secretFile(cluster, namespace, name)
+
+	var buf []byte
+	if buf, err = ioutil.ReadFile(file); err != nil {
+		if !os.IsNotExist(err) {
+			return
+		}
+		err = nil
+		buf = []byte("type: Opaque\nstringData: {}\n")
+	}
+	if buf, err = YAML2JSON(buf); err != nil {
+		return
+	}
+	if buf, err = RevealSecretJSON(buf, namespace, name); err != nil {
+		return
+	}
+	if buf, err = JSON2YAML(buf); err != nil {
+		return
+	}
+
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile("", "koop-secret-*.yaml"); err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf)
+	_ = tmp.Close()
+	if err != nil {
+		return
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], tmp.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return
+	}
+
+	var edited []byte
+	if edited, err = ioutil.ReadFile(tmp.Name()); err != nil {
+		return
+	}
+	if bytes.Equal(edited, buf) {
+		log.Println("UNCHANGED:", file)
+		return
+	}
+	if edited, err = YAML2JSON(edited); err != nil {
+		return
+	}
+	if edited, err = ConcealSecretJSON(edited, namespace, name); err != nil {
+		return
+	}
+	if edited, err = JSON2YAML(edited); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(file, edited, 0600); err != nil {
+		return
+	}
+	log.Println("SAVED:", file)
+	return
+}
+
+// commandSanitizers prints the effective sanitizers of kinds in YAML
+func commandSanitizers(ctx context.Context, cluster string, kind string) (err error) {
+	return iterateCluster(NewPool(ctx, 1), cluster, func(cluster string, client *Client) (err error) {
+		var resources []*Resource
+		if resources, err = resolveResources(client, cluster, kind); err != nil {
+			return
+		}
+		for _, resource := range resources {
+			var buf []byte
+			if buf, err = yaml.Marshal(koopConfig.SanitizersFor(cluster, resource)); err != nil {
+				return
+			}
+			fmt.Printf("# %s/%s\n%s", cluster, resource.Kind, buf)
+		}
+		return
+	})
+}
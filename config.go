@@ -0,0 +1,208 @@
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	configFile      = "config.yaml"
+	localConfigFile = ".koop.yaml"
+)
+
+var (
+	defaultIgnoredNamespaces = []string{
+		"cattle-prometheus",
+		"cattle-system",
+		"kube-system",
+		"kube-public",
+		"kube-ingress",
+		"kube-storage",
+		"kube-node-lease",
+		"nginx-ingress",
+		"ingress-nginx",
+		"nfs-client-provisioner",
+		"security-scan",
+		"nfs-provisioner",
+		"autoops",
+		"istio-system",
+	}
+
+	// defaultManagedLabels and defaultManagedAnnotations mark objects managed by Helm, Kustomize, Argo CD and secret replicator
+	defaultManagedLabels = []string{
+		"app.kubernetes.io/managed-by=Helm",
+		"app.kubernetes.io/managed-by=kustomize*",
+		"argocd.argoproj.io/instance",
+	}
+	defaultManagedAnnotations = []string{
+		"argocd.argoproj.io/tracking-id",
+		"autoops.auto-replicate-secret/replicated={1,t,T,true,True,TRUE}",
+	}
+
+	koopConfig = &Config{}
+)
+
+type ClusterConfig struct {
+	// IgnoredNamespaces are appended to the global ignored namespaces
+	IgnoredNamespaces []string `yaml:"ignoredNamespaces"`
+	// Kinds replaces the global kinds
+	Kinds []string `yaml:"kinds"`
+	// ManagedLabels and ManagedAnnotations are appended to the global ones
+	ManagedLabels      []string `yaml:"managedLabels"`
+	ManagedAnnotations []string `yaml:"managedAnnotations"`
+	// Sanitizers are appended to the global sanitizers of the same kind
+	Sanitizers map[string]PatchSet `yaml:"sanitizers"`
+}
+
+type Config struct {
+	// DisableDefaultIgnoredNamespaces drops the built-in ignored namespaces
+	DisableDefaultIgnoredNamespaces bool `yaml:"disableDefaultIgnoredNamespaces"`
+	// IgnoredNamespaces are namespace patterns skipped by wildcard namespace, a pattern without glob characters matches as prefix
+	IgnoredNamespaces []string `yaml:"ignoredNamespaces"`
+	// Kinds are the kinds visited by wildcard kind, all built-in kinds if empty
+	Kinds []string `yaml:"kinds"`
+	// DisableDefaultManaged drops the built-in managed labels and annotations
+	DisableDefaultManaged bool `yaml:"disableDefaultManaged"`
+	// ManagedLabels and ManagedAnnotations mark objects managed by other systems, which are skipped unless --include-managed,
+	// a rule is 'key' for any value or 'key=pattern', see ParsePattern
+	ManagedLabels      []string `yaml:"managedLabels"`
+	ManagedAnnotations []string `yaml:"managedAnnotations"`
+	// Sanitizers are extra patches applied after the built-in sanitizers on pull and push, keyed by kind, alias or '-' for all kinds
+	Sanitizers map[string]PatchSet      `yaml:"sanitizers"`
+	Clusters   map[string]ClusterConfig `yaml:"clusters"`
+}
+
+// Merge overlays another config, lists of namespaces are appended, kinds are replaced
+func (c *Config) Merge(o Config) {
+	c.DisableDefaultIgnoredNamespaces = c.DisableDefaultIgnoredNamespaces || o.DisableDefaultIgnoredNamespaces
+	c.IgnoredNamespaces = append(c.IgnoredNamespaces, o.IgnoredNamespaces...)
+	if len(o.Kinds) > 0 {
+		c.Kinds = o.Kinds
+	}
+	c.DisableDefaultManaged = c.DisableDefaultManaged || o.DisableDefaultManaged
+	c.ManagedLabels = append(c.ManagedLabels, o.ManagedLabels...)
+	c.ManagedAnnotations = append(c.ManagedAnnotations, o.ManagedAnnotations...)
+	c.Sanitizers = mergeSanitizers(c.Sanitizers, o.Sanitizers)
+	for name, oc := range o.Clusters {
+		if c.Clusters == nil {
+			c.Clusters = map[string]ClusterConfig{}
+		}
+		cc := c.Clusters[name]
+		cc.IgnoredNamespaces = append(cc.IgnoredNamespaces, oc.IgnoredNamespaces...)
+		cc.ManagedLabels = append(cc.ManagedLabels, oc.ManagedLabels...)
+		cc.ManagedAnnotations = append(cc.ManagedAnnotations, oc.ManagedAnnotations...)
+		cc.Sanitizers = mergeSanitizers(cc.Sanitizers, oc.Sanitizers)
+		if len(oc.Kinds) > 0 {
+			cc.Kinds = oc.Kinds
+		}
+		c.Clusters[name] = cc
+	}
+}
+
+func (c *Config) IgnoredNamespacesFor(cluster string) (patterns []string) {
+	if !c.DisableDefaultIgnoredNamespaces {
+		patterns = append(patterns, defaultIgnoredNamespaces...)
+	}
+	patterns = append(patterns, c.IgnoredNamespaces...)
+	patterns = append(patterns, c.Clusters[cluster].IgnoredNamespaces...)
+	return
+}
+
+// ManagedRulesFor returns rules of labels and annotations marking managed objects
+func (c *Config) ManagedRulesFor(cluster string) (labels []string, annotations []string) {
+	if !c.DisableDefaultManaged {
+		labels = append(labels, defaultManagedLabels...)
+		annotations = append(annotations, defaultManagedAnnotations...)
+	}
+	labels = append(labels, c.ManagedLabels...)
+	labels = append(labels, c.Clusters[cluster].ManagedLabels...)
+	annotations = append(annotations, c.ManagedAnnotations...)
+	annotations = append(annotations, c.Clusters[cluster].ManagedAnnotations...)
+	return
+}
+
+func mergeSanitizers(a, b map[string]PatchSet) map[string]PatchSet {
+	for kind, ps := range b {
+		if a == nil {
+			a = map[string]PatchSet{}
+		}
+		a[kind] = append(a[kind], ps...)
+	}
+	return a
+}
+
+// SanitizersFor returns the effective sanitizers of a resource, the built-in ones, then global ones, then ones of the cluster;
+// within each, ones for all kinds go first
+func (c *Config) SanitizersFor(cluster string, resource *Resource) PatchSet {
+	sanitizers := append(PatchSet{}, defaultSanitizers...)
+	for _, m := range []map[string]PatchSet{c.Sanitizers, c.Clusters[cluster].Sanitizers} {
+		sanitizers = append(sanitizers, m[nameAny]...)
+		var kinds []string
+		for kind := range m {
+			if kind != nameAny && resource.Matches(kind) {
+				kinds = append(kinds, kind)
+			}
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			sanitizers = append(sanitizers, m[kind]...)
+		}
+	}
+	return sanitizers
+}
+
+func (c *Config) KindsFor(cluster string) []string {
+	if kinds := c.Clusters[cluster].Kinds; len(kinds) > 0 {
+		return kinds
+	}
+	if len(c.Kinds) > 0 {
+		return c.Kinds
+	}
+	return knownResourceNames
+}
+
+func (c *Config) IsNamespaceIgnored(cluster string, namespace string) bool {
+	namespace = strings.ToLower(namespace)
+	for _, pattern := range c.IgnoredNamespacesFor(cluster) {
+		if strings.HasPrefix(pattern, patternRegexpPrefix) || strings.ContainsAny(pattern, patternGlobChars) {
+			if p, err := ParsePattern(pattern); err == nil && p.Match(namespace) {
+				return true
+			}
+		} else if strings.HasPrefix(namespace, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadConfigFile(file string) (cfg Config, err error) {
+	var buf []byte
+	if buf, err = ioutil.ReadFile(file); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = yaml.Unmarshal(buf, &cfg)
+	return
+}
+
+// loadConfig loads $HOME/.koop/config.yaml, then overlays .koop.yaml in current directory
+func loadConfig() (err error) {
+	var home string
+	if home, err = os.UserHomeDir(); err != nil {
+		return
+	}
+	for _, file := range []string{filepath.Join(home, configDir, configFile), localConfigFile} {
+		var cfg Config
+		if cfg, err = loadConfigFile(file); err != nil {
+			return
+		}
+		koopConfig.Merge(cfg)
+	}
+	return
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	keysDir        = "keys"
+	defaultKeyFile = "default.key"
+
+	encryptedPrefix = "koop:aes256gcm-hkdf:"
+
+	// infos of HKDF subkeys derived from the master key
+	encryptionKeyInfo = "koop secret encryption"
+	nonceKeyInfo      = "koop secret nonce"
+)
+
+var (
+	secretKey     []byte
+	secretKeyLock sync.Mutex
+)
+
+func keyFile() (file string, err error) {
+	var home string
+	if home, err = os.UserHomeDir(); err != nil {
+		return
+	}
+	file = filepath.Join(home, configDir, keysDir, defaultKeyFile)
+	return
+}
+
+// loadSecretKey loads the 256-bit key from env KOOP_SECRET_KEY or $HOME/.koop/keys/default.key, both base64 encoded,
+// a new key file is generated if none exists and create is true
+func loadSecretKey(create bool) (key []byte, err error) {
+	secretKeyLock.Lock()
+	defer secretKeyLock.Unlock()
+	if secretKey != nil {
+		key = secretKey
+		return
+	}
+	encoded := strings.TrimSpace(os.Getenv("KOOP_SECRET_KEY"))
+	if encoded == "" {
+		var file string
+		if file, err = keyFile(); err != nil {
+			return
+		}
+		var buf []byte
+		if buf, err = ioutil.ReadFile(file); err != nil {
+			if !os.IsNotExist(err) || !create {
+				err = fmt.Errorf("failed to load secret key, set env KOOP_SECRET_KEY or create %s: %s", file, err.Error())
+				return
+			}
+			key = make([]byte, 32)
+			if _, err = rand.Read(key); err != nil {
+				return
+			}
+			if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+				return
+			}
+			if err = ioutil.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+				return
+			}
+			log.Println("generated secret key:", file, ", keep it safe, encrypted secrets can not be recovered without it")
+			secretKey = key
+			return
+		}
+		encoded = strings.TrimSpace(string(buf))
+	}
+	if key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return
+	}
+	if len(key) != 32 {
+		err = errors.New("secret key must be 32 bytes, base64 encoded")
+		return
+	}
+	secretKey = key
+	return
+}
+
+// deriveSubkey derives a 256-bit subkey of the master key for info with HKDF-SHA256, see RFC 5869,
+// so that the AES key and the HMAC key deriving nonces are independent
+func deriveSubkey(key []byte, info string) []byte {
+	// extract with an empty salt, which is a string of zeros of hash length
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(key)
+	// a single block of expand is 32 bytes
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(info))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
+
+func newSecretCipher(key []byte) (aead cipher.AEAD, err error) {
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+	aead, err = cipher.NewGCM(block)
+	return
+}
+
+// secretValueAAD binds an encrypted value to its place, 'namespace/name/key', so that it can not be moved to another secret or key
+func secretValueAAD(namespace, name, key string) []byte {
+	return []byte(namespace + "/" + name + "/" + key)
+}
+
+// EncryptValue encrypts a value with AES-256-GCM under a subkey of key, authenticating aad, the nonce is a HMAC of aad and
+// the plaintext under another subkey, so that unchanged values produce unchanged ciphertext and diffs stay clean; encryption
+// is deterministic, equal plaintexts at the same place produce equal ciphertexts, which tells readers of local files which
+// values are unchanged, but nothing else about them
+func EncryptValue(key []byte, plain []byte, aad []byte) (out string, err error) {
+	var aead cipher.AEAD
+	if aead, err = newSecretCipher(deriveSubkey(key, encryptionKeyInfo)); err != nil {
+		return
+	}
+	// aad is length prefixed, so that no other split of the same bytes gives the same nonce
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(aad)))
+	mac := hmac.New(sha256.New, deriveSubkey(key, nonceKeyInfo))
+	mac.Write(size)
+	mac.Write(aad)
+	mac.Write(plain)
+	nonce := mac.Sum(nil)[:aead.NonceSize()]
+	out = encryptedPrefix + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, aad))
+	return
+}
+
+func DecryptValue(key []byte, value string, aad []byte) (plain []byte, err error) {
+	var buf []byte
+	if buf, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix)); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = newSecretCipher(deriveSubkey(key, encryptionKeyInfo)); err != nil {
+		return
+	}
+	if len(buf) < aead.NonceSize() {
+		err = errors.New("encrypted value is too short")
+		return
+	}
+	if plain, err = aead.Open(nil, buf[:aead.NonceSize()], buf[aead.NonceSize():], aad); err != nil {
+		err = fmt.Errorf("failed to decrypt value, wrong secret key or moved from another secret? %s", err.Error())
+		return
+	}
+	return
+}
+
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// transformSecretData calls fn for each value in '/data'
+func transformSecretData(buf []byte, fn func(m map[string]interface{}, key string, value string) error) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(buf, &m); err != nil {
+		return
+	}
+	data, _ := m["data"].(map[string]interface{})
+	for key, value := range data {
+		s, _ := value.(string)
+		if err = fn(m, key, s); err != nil {
+			return
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
+
+// EncryptSecretJSON encrypts values in '/data' of secret namespace/name, values already encrypted are kept as is
+func EncryptSecretJSON(buf []byte, namespace, name string) (out []byte, err error) {
+	var key []byte
+	if key, err = loadSecretKey(true); err != nil {
+		return
+	}
+	return transformSecretData(buf, func(m map[string]interface{}, k string, value string) (err error) {
+		if IsEncryptedValue(value) {
+			return
+		}
+		var plain []byte
+		if plain, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return
+		}
+		m["data"].(map[string]interface{})[k], err = EncryptValue(key, plain, secretValueAAD(namespace, name, k))
+		return
+	})
+}
+
+// DecryptSecretJSON decrypts values in '/data' of secret namespace/name, values not encrypted are kept as is
+func DecryptSecretJSON(buf []byte, namespace, name string) (out []byte, err error) {
+	var key []byte
+	return transformSecretData(buf, func(m map[string]interface{}, k string, value string) (err error) {
+		if !IsEncryptedValue(value) {
+			return
+		}
+		if key == nil {
+			if key, err = loadSecretKey(false); err != nil {
+				return
+			}
+		}
+		var plain []byte
+		if plain, err = DecryptValue(key, value, secretValueAAD(namespace, name, k)); err != nil {
+			return
+		}
+		m["data"].(map[string]interface{})[k] = base64.StdEncoding.EncodeToString(plain)
+		return
+	})
+}
+
+// RevealSecretJSON decrypts values in '/data' of secret namespace/name, and moves UTF-8 values to '/stringData' in plain text
+func RevealSecretJSON(buf []byte, namespace, name string) (out []byte, err error) {
+	if buf, err = DecryptSecretJSON(buf, namespace, name); err != nil {
+		return
+	}
+	return transformSecretData(buf, func(m map[string]interface{}, k string, value string) (err error) {
+		var plain []byte
+		if plain, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return
+		}
+		if !utf8.Valid(plain) {
+			return
+		}
+		stringData, _ := m["stringData"].(map[string]interface{})
+		if stringData == nil {
+			stringData = map[string]interface{}{}
+			m["stringData"] = stringData
+		}
+		stringData[k] = string(plain)
+		data := m["data"].(map[string]interface{})
+		if delete(data, k); len(data) == 0 {
+			delete(m, "data")
+		}
+		return
+	})
+}
+
+// ConcealSecretJSON reverses RevealSecretJSON, moves '/stringData' back to '/data' and encrypts all values
+func ConcealSecretJSON(buf []byte, namespace, name string) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(buf, &m); err != nil {
+		return
+	}
+	if stringData, ok := m["stringData"].(map[string]interface{}); ok {
+		data, _ := m["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+			m["data"] = data
+		}
+		for k, v := range stringData {
+			data[k] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(v)))
+		}
+		delete(m, "stringData")
+	}
+	if buf, err = json.Marshal(m); err != nil {
+		return
+	}
+	return EncryptSecretJSON(buf, namespace, name)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestEncryptValue(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	otherKey := bytes.Repeat([]byte{2}, 32)
+	aad := secretValueAAD("default", "app", "password")
+	tests := []struct {
+		name       string
+		plain      string
+		decryptKey []byte
+		decryptAAD []byte
+		fail       bool
+	}{
+		{name: "round trip", plain: "secret", decryptKey: key, decryptAAD: aad},
+		{name: "empty value", plain: "", decryptKey: key, decryptAAD: aad},
+		{name: "wrong key", plain: "secret", decryptKey: otherKey, decryptAAD: aad, fail: true},
+		{name: "other secret", plain: "secret", decryptKey: key, decryptAAD: secretValueAAD("default", "other", "password"), fail: true},
+		{name: "other key of secret", plain: "secret", decryptKey: key, decryptAAD: secretValueAAD("default", "app", "token"), fail: true},
+	}
+	for _, test := range tests {
+		encrypted, err := EncryptValue(key, []byte(test.plain), aad)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if !IsEncryptedValue(encrypted) {
+			t.Errorf("%s: %s is not an encrypted value", test.name, encrypted)
+		}
+		again, err := EncryptValue(key, []byte(test.plain), aad)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if again != encrypted {
+			t.Errorf("%s: not deterministic, got %s and %s", test.name, encrypted, again)
+		}
+		plain, err := DecryptValue(test.decryptKey, encrypted, test.decryptAAD)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: decrypted to %q, want error", test.name, plain)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if string(plain) != test.plain {
+			t.Errorf("%s: got %q, want %q", test.name, plain, test.plain)
+		}
+	}
+}
+
+func TestEncryptValueDiffers(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	tests := []struct {
+		name   string
+		plain1 string
+		aad1   []byte
+		plain2 string
+		aad2   []byte
+	}{
+		{"other value", "a", secretValueAAD("ns", "app", "k"), "b", secretValueAAD("ns", "app", "k")},
+		{"other key of secret", "a", secretValueAAD("ns", "app", "k"), "a", secretValueAAD("ns", "app", "j")},
+		{"other namespace", "a", secretValueAAD("ns", "app", "k"), "a", secretValueAAD("other", "app", "k")},
+	}
+	for _, test := range tests {
+		out1, err := EncryptValue(key, []byte(test.plain1), test.aad1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out2, err := EncryptValue(key, []byte(test.plain2), test.aad2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out1 == out2 {
+			t.Errorf("%s: equal ciphertext %s", test.name, out1)
+		}
+	}
+}
+
+func TestRevealConcealSecretJSON(t *testing.T) {
+	secretKeyLock.Lock()
+	saved := secretKey
+	secretKey = bytes.Repeat([]byte{3}, 32)
+	secretKeyLock.Unlock()
+	defer func() {
+		secretKeyLock.Lock()
+		secretKey = saved
+		secretKeyLock.Unlock()
+	}()
+
+	binary := base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe})
+	tests := []struct {
+		name     string
+		in       string
+		revealed string
+	}{
+		{
+			name:     "utf-8 values",
+			in:       `{"type": "Opaque", "data": {"user": "` + base64.StdEncoding.EncodeToString([]byte("admin")) + `"}}`,
+			revealed: `{"type": "Opaque", "stringData": {"user": "admin"}}`,
+		},
+		{
+			name:     "binary values stay in data",
+			in:       `{"data": {"user": "` + base64.StdEncoding.EncodeToString([]byte("admin")) + `", "blob": "` + binary + `"}}`,
+			revealed: `{"data": {"blob": "` + binary + `"}, "stringData": {"user": "admin"}}`,
+		},
+		{
+			name:     "no data",
+			in:       `{"type": "Opaque"}`,
+			revealed: `{"type": "Opaque"}`,
+		},
+	}
+	for _, test := range tests {
+		encrypted, err := EncryptSecretJSON([]byte(test.in), "default", "app")
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		revealed, err := RevealSecretJSON(encrypted, "default", "app")
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if same, err := EqualJSON(revealed, []byte(test.revealed)); err != nil || !same {
+			t.Errorf("%s: revealed %s, want %s", test.name, revealed, test.revealed)
+			continue
+		}
+		concealed, err := ConcealSecretJSON(revealed, "default", "app")
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if same, err := EqualJSON(concealed, encrypted); err != nil || !same {
+			t.Errorf("%s: concealed %s, want %s", test.name, concealed, encrypted)
+		}
+		if _, err := RevealSecretJSON(encrypted, "default", "other"); err == nil && bytes.Contains(encrypted, []byte(encryptedPrefix)) {
+			t.Errorf("%s: revealed as another secret", test.name)
+		}
+	}
+}
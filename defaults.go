@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// removeDefault removes a value at path if it equals the value defaulted by kubernetes
+func removeDefault(path string, value interface{}) Patches {
+	return Patches{{Op: OpTest, Path: path, Value: value}, {Op: OpRemove, Path: path}}
+}
+
+var (
+	// defaults of container, see SetDefaults_Container, SetDefaults_Probe and SetDefaults_ContainerPort in k8s.io/kubernetes/pkg/apis/core/v1
+	containerDefaults = PatchSet{
+		removeDefault("/terminationMessagePath", "/dev/termination-log"),
+		removeDefault("/terminationMessagePolicy", "File"),
+		removeDefault("/resources", map[string]interface{}{}),
+		removeDefault("/ports/*/protocol", "TCP"),
+		removeDefault("/env/*/valueFrom/fieldRef/apiVersion", "v1"),
+		removeDefault("/livenessProbe/timeoutSeconds", 1),
+		removeDefault("/livenessProbe/periodSeconds", 10),
+		removeDefault("/livenessProbe/successThreshold", 1),
+		removeDefault("/livenessProbe/failureThreshold", 3),
+		removeDefault("/readinessProbe/timeoutSeconds", 1),
+		removeDefault("/readinessProbe/periodSeconds", 10),
+		removeDefault("/readinessProbe/successThreshold", 1),
+		removeDefault("/readinessProbe/failureThreshold", 3),
+		removeDefault("/startupProbe/timeoutSeconds", 1),
+		removeDefault("/startupProbe/periodSeconds", 10),
+		removeDefault("/startupProbe/successThreshold", 1),
+		removeDefault("/startupProbe/failureThreshold", 3),
+	}
+
+	// defaults of pod spec, see SetDefaults_PodSpec and SetDefaults_Volume in k8s.io/kubernetes/pkg/apis/core/v1
+	podSpecDefaults = PatchSet{
+		removeDefault("/restartPolicy", "Always"),
+		removeDefault("/dnsPolicy", "ClusterFirst"),
+		removeDefault("/terminationGracePeriodSeconds", 30),
+		removeDefault("/securityContext", map[string]interface{}{}),
+		removeDefault("/schedulerName", "default-scheduler"),
+		removeDefault("/enableServiceLinks", true),
+		removeDefault("/volumes/*/configMap/defaultMode", 420),
+		removeDefault("/volumes/*/secret/defaultMode", 420),
+		removeDefault("/volumes/*/downwardAPI/defaultMode", 420),
+		removeDefault("/volumes/*/projected/defaultMode", 420),
+	}
+)
+
+// prefixPatchSet moves patches under a JSON pointer
+func prefixPatchSet(prefix string, ps PatchSet) (out PatchSet) {
+	for _, patches := range ps {
+		var group Patches
+		for _, p := range patches {
+			p.Path = prefix + p.Path
+			group = append(group, p)
+		}
+		out = append(out, group)
+	}
+	return
+}
+
+// podTemplateDefaults returns patches removing server defaults of a pod template at prefix
+func podTemplateDefaults(prefix string) (out PatchSet) {
+	out = append(out, prefixPatchSet(prefix+"/spec", podSpecDefaults)...)
+	out = append(out, prefixPatchSet(prefix+"/spec/containers/*", containerDefaults)...)
+	out = append(out, prefixPatchSet(prefix+"/spec/initContainers/*", containerDefaults)...)
+	return
+}
+
+// defaultImagePullPolicy returns the pull policy defaulted for an image, 'Always' for tag 'latest' or no tag, 'IfNotPresent' otherwise
+func defaultImagePullPolicy(image string) string {
+	if strings.Contains(image, "@") {
+		return "IfNotPresent"
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i < 0 || name[i+1:] == "latest" {
+		return "Always"
+	}
+	return "IfNotPresent"
+}
+
+// removeDefaultImagePullPolicy removes 'imagePullPolicy' of containers in a pod template if it equals the default of the image
+func removeDefaultImagePullPolicy(data []byte, prefix string) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	var node interface{} = m
+	for _, key := range strings.Split(strings.TrimPrefix(prefix+"/spec", "/"), "/") {
+		parent, _ := node.(map[string]interface{})
+		node = parent[key]
+	}
+	spec, _ := node.(map[string]interface{})
+	for _, key := range []string{"containers", "initContainers"} {
+		containers, _ := spec[key].([]interface{})
+		for _, container := range containers {
+			container, _ := container.(map[string]interface{})
+			image, _ := container["image"].(string)
+			if policy, ok := container["imagePullPolicy"].(string); ok && image != "" && policy == defaultImagePullPolicy(image) {
+				delete(container, "imagePullPolicy")
+			}
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
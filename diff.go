@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	diffContext = 3
+
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+	colorBold  = "\x1b[1m"
+)
+
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+func splitLines(buf []byte) []string {
+	s := strings.TrimSuffix(string(buf), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func diffLines(a, b []string) (out []diffLine) {
+	// classic LCS table, manifests are small enough
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			out = append(out, diffLine{Op: ' ', Text: a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			out = append(out, diffLine{Op: '-', Text: a[i]})
+			i++
+		} else {
+			out = append(out, diffLine{Op: '+', Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{Op: '-', Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{Op: '+', Text: b[j]})
+	}
+	return
+}
+
+// UnifiedDiff renders a unified diff between two documents, returns empty string if they are identical
+func UnifiedDiff(fromName, toName string, from, to []byte, color bool) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	paint := func(c, s string) string {
+		if color {
+			return c + s + colorReset
+		}
+		return s
+	}
+
+	out := &bytes.Buffer{}
+	// line numbers (1-based) of lines[i] in from and to
+	fromNo, toNo := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	fromNo[0], toNo[0] = 1, 1
+	for i, l := range lines {
+		fromNo[i+1], toNo[i+1] = fromNo[i], toNo[i]
+		if l.Op != '+' {
+			fromNo[i+1]++
+		}
+		if l.Op != '-' {
+			toNo[i+1]++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == ' ' {
+			i++
+			continue
+		}
+		// expand hunk until diffContext*2 unchanged lines in a row
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != ' ' {
+				end++
+				continue
+			}
+			k := end
+			for k < len(lines) && lines[k].Op == ' ' {
+				k++
+			}
+			if k == len(lines) || k-end > diffContext*2 {
+				break
+			}
+			end = k
+		}
+		stop := end + diffContext
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		if out.Len() == 0 {
+			out.WriteString(paint(colorBold, "--- "+fromName) + "\n")
+			out.WriteString(paint(colorBold, "+++ "+toName) + "\n")
+		}
+		fromCount, toCount := fromNo[stop]-fromNo[start], toNo[stop]-toNo[start]
+		fromStart, toStart := fromNo[start], toNo[start]
+		if fromCount == 0 {
+			fromStart--
+		}
+		if toCount == 0 {
+			toStart--
+		}
+		out.WriteString(paint(colorCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", fromStart, fromCount, toStart, toCount)) + "\n")
+		for _, l := range lines[start:stop] {
+			switch l.Op {
+			case '-':
+				out.WriteString(paint(colorRed, "-"+l.Text) + "\n")
+			case '+':
+				out.WriteString(paint(colorGreen, "+"+l.Text) + "\n")
+			default:
+				out.WriteString(" " + l.Text + "\n")
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// IsColorTerminal checks whether stdout is a terminal that accepts ANSI colors
+func IsColorTerminal() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	patternRegexpPrefix = "re:"
+	patternGlobChars    = "*?[{"
+)
+
+// Pattern matches names of clusters, namespaces and objects, '-' matches any name, 're:' prefix starts a regular expression,
+// a pattern with any of '*?[{' is a glob supporting '{a,b}' alternatives, other patterns match the name literally
+type Pattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+func ParsePattern(s string) (p Pattern, err error) {
+	p.raw = s
+	if s == nameAny {
+		return
+	}
+	if strings.HasPrefix(s, patternRegexpPrefix) {
+		if p.re, err = regexp.Compile(strings.TrimPrefix(s, patternRegexpPrefix)); err != nil {
+			err = fmt.Errorf("invalid pattern '%s': %s", s, err.Error())
+		}
+		return
+	}
+	if strings.ContainsAny(s, patternGlobChars) {
+		var expr string
+		if expr, err = globToRegexp(s); err != nil {
+			err = fmt.Errorf("invalid pattern '%s': %s", s, err.Error())
+			return
+		}
+		if p.re, err = regexp.Compile(expr); err != nil {
+			err = fmt.Errorf("invalid pattern '%s': %s", s, err.Error())
+		}
+		return
+	}
+	return
+}
+
+// IsLiteral checks whether the pattern matches exactly one name
+func (p Pattern) IsLiteral() bool {
+	return p.raw != nameAny && p.re == nil
+}
+
+func (p Pattern) Match(name string) bool {
+	if p.raw == nameAny {
+		return true
+	}
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	return p.raw == name
+}
+
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// globToRegexp converts a glob to an anchored regular expression, '*' matches any characters, '?' matches one character,
+// '[...]' and '[!...]' match character classes, '{a,b}' matches alternatives, which can be nested
+func globToRegexp(glob string) (string, error) {
+	sb := &strings.Builder{}
+	sb.WriteString("^")
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*':
+			sb.WriteString(".*")
+		case c == '?':
+			sb.WriteString(".")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated '['")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + strings.TrimPrefix(class, "!")
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '{':
+			depth++
+			sb.WriteString("(?:")
+		case c == '}' && depth > 0:
+			depth--
+			sb.WriteString(")")
+		case c == ',' && depth > 0:
+			sb.WriteString("|")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("unterminated '{'")
+	}
+	sb.WriteString("$")
+	return sb.String(), nil
+}
+
+// Filter selects objects visited by commands
+type Filter struct {
+	// Excludes are patterns of object names to skip
+	Excludes []string
+	// Selector and FieldSelector are passed to the server on listing, and evaluated against local files
+	Selector      string
+	FieldSelector string
+	// IncludeManaged keeps objects owned by controllers or managed by other systems, see Config.ManagedLabels
+	IncludeManaged bool
+
+	name          Pattern
+	excludes      []Pattern
+	selector      labels.Selector
+	fieldSelector fields.Selector
+	// managed are compiled managed rules by cluster, '' for clusters without own rules
+	managed map[string]managedRules
+
+	mu      sync.Mutex
+	skipped map[string]int
+}
+
+// Compile compiles the NAME argument and exclusions
+func (f *Filter) Compile(name string) (err error) {
+	if f.name, err = ParsePattern(name); err != nil {
+		return
+	}
+	f.excludes = nil
+	for _, exclude := range f.Excludes {
+		var p Pattern
+		if p, err = ParsePattern(exclude); err != nil {
+			return
+		}
+		f.excludes = append(f.excludes, p)
+	}
+	if f.selector, err = labels.Parse(f.Selector); err != nil {
+		err = fmt.Errorf("invalid selector '%s': %s", f.Selector, err.Error())
+		return
+	}
+	if f.fieldSelector, err = fields.ParseSelector(f.FieldSelector); err != nil {
+		err = fmt.Errorf("invalid field selector '%s': %s", f.FieldSelector, err.Error())
+		return
+	}
+	f.managed = map[string]managedRules{}
+	clusters := []string{""}
+	for cluster := range koopConfig.Clusters {
+		clusters = append(clusters, cluster)
+	}
+	for _, cluster := range clusters {
+		if f.managed[cluster], err = compileManagedRules(koopConfig.ManagedRulesFor(cluster)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ListOptions returns options to list objects selected by server
+func (f *Filter) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: f.Selector, FieldSelector: f.FieldSelector}
+}
+
+// HasSelectors checks whether objects are selected by labels or fields
+func (f *Filter) HasSelectors() bool {
+	return f.Selector != "" || f.FieldSelector != ""
+}
+
+// MatchJSON evaluates selectors against an object in JSON, local files have no name and namespace, they are taken from path
+func (f *Filter) MatchJSON(data []byte, namespace, name string) (ok bool, err error) {
+	if !f.HasSelectors() {
+		ok = true
+		return
+	}
+	var obj jsonFields
+	if err = json.Unmarshal(data, &obj); err != nil {
+		return
+	}
+	if obj == nil {
+		obj = jsonFields{}
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	metadata["name"] = name
+	if namespace != namespaceCluster {
+		metadata["namespace"] = namespace
+	}
+	set := labels.Set{}
+	if items, ok := metadata["labels"].(map[string]interface{}); ok {
+		for key, value := range items {
+			set[key] = fmt.Sprint(value)
+		}
+	}
+	ok = f.selector.Matches(set) && f.fieldSelector.Matches(obj)
+	return
+}
+
+// MatchYAML evaluates selectors against a local file
+func (f *Filter) MatchYAML(data []byte, namespace, name string) (ok bool, err error) {
+	if !f.HasSelectors() {
+		ok = true
+		return
+	}
+	if data, err = YAML2JSON(data); err != nil {
+		return
+	}
+	return f.MatchJSON(data, namespace, name)
+}
+
+// IsLiteral checks whether the NAME argument names exactly one object
+func (f *Filter) IsLiteral() bool {
+	return f.name.IsLiteral()
+}
+
+// Match checks whether an object name matches the NAME argument and is not excluded
+func (f *Filter) Match(name string) bool {
+	if !f.name.Match(name) {
+		return false
+	}
+	for _, exclude := range f.excludes {
+		if exclude.Match(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Names keeps matched names
+func (f *Filter) Names(names []string) (out []string) {
+	for _, name := range names {
+		if f.Match(name) {
+			out = append(out, name)
+		}
+	}
+	return
+}
+
+// Objects keeps matched listed objects, namespace objects of ignored namespaces and managed objects are dropped
+func (f *Filter) Objects(cluster string, resource *Resource, objects []Object) (out []Object) {
+	out, _ = f.Split(cluster, resource, objects)
+	return
+}
+
+// Split is Objects also returning names of dropped managed objects, whose local files must be left alone
+func (f *Filter) Split(cluster string, resource *Resource, objects []Object) (out []Object, managed []string) {
+	rules, ok := f.managed[cluster]
+	if !ok {
+		rules = f.managed[""]
+	}
+	for _, object := range objects {
+		if resource.Kind == kindNamespace && koopConfig.IsNamespaceIgnored(cluster, object.Name) {
+			continue
+		}
+		if !f.Match(object.Name) {
+			continue
+		}
+		if !f.IncludeManaged {
+			if reason := rules.reason(object.JSON); reason != "" {
+				managed = append(managed, object.Name)
+				f.mu.Lock()
+				if f.skipped == nil {
+					f.skipped = map[string]int{}
+				}
+				f.skipped[reason]++
+				f.mu.Unlock()
+				continue
+			}
+		}
+		out = append(out, object)
+	}
+	return
+}
+
+// LogSkipped prints a summary of skipped managed objects by reason
+func (f *Filter) LogSkipped() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.skipped) == 0 {
+		return
+	}
+	var total int
+	var reasons []string
+	for reason, count := range f.skipped {
+		total += count
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	log.Printf("SKIPPED: %d managed objects, use --include-managed to include them", total)
+	for _, reason := range reasons {
+		log.Printf("SKIPPED: %d %s", f.skipped[reason], reason)
+	}
+}
+
+// managedRule is a compiled rule 'key' or 'key=pattern' matching labels or annotations
+type managedRule struct {
+	raw   string
+	key   string
+	value Pattern
+}
+
+type managedRules struct {
+	labels      []managedRule
+	annotations []managedRule
+}
+
+func compileManagedRules(labelRules, annotationRules []string) (rules managedRules, err error) {
+	if rules.labels, err = compileManagedRuleList(labelRules); err != nil {
+		return
+	}
+	rules.annotations, err = compileManagedRuleList(annotationRules)
+	return
+}
+
+func compileManagedRuleList(raws []string) (rules []managedRule, err error) {
+	for _, raw := range raws {
+		rule := managedRule{raw: raw, key: raw}
+		pattern := nameAny
+		if i := strings.Index(raw, "="); i >= 0 {
+			rule.key, pattern = raw[:i], raw[i+1:]
+		}
+		if rule.value, err = ParsePattern(pattern); err != nil {
+			err = fmt.Errorf("invalid managed rule '%s': %s", raw, err.Error())
+			return
+		}
+		rules = append(rules, rule)
+	}
+	return
+}
+
+func (r managedRule) match(values map[string]string) bool {
+	value, ok := values[r.key]
+	return ok && r.value.Match(value)
+}
+
+// reason tells why an object is managed by a controller or another system, empty if it is not
+func (r managedRules) reason(data []byte) string {
+	var obj struct {
+		Metadata struct {
+			OwnerReferences []metav1.OwnerReference `json:"ownerReferences"`
+			Labels          map[string]string       `json:"labels"`
+			Annotations     map[string]string       `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return ""
+	}
+	if refs := obj.Metadata.OwnerReferences; len(refs) > 0 {
+		return "owned by " + refs[0].Kind
+	}
+	for _, rule := range r.labels {
+		if rule.match(obj.Metadata.Labels) {
+			return "with label " + rule.raw
+		}
+	}
+	for _, rule := range r.annotations {
+		if rule.match(obj.Metadata.Annotations) {
+			return "with annotation " + rule.raw
+		}
+	}
+	return ""
+}
+
+// jsonFields resolves dotted field paths of a field selector, like 'metadata.name' or 'spec.nodeName', in an object
+type jsonFields map[string]interface{}
+
+func (j jsonFields) lookup(field string) (value string, found bool) {
+	var current interface{} = map[string]interface{}(j)
+	for _, key := range strings.Split(field, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if current, ok = m[key]; !ok {
+			return
+		}
+	}
+	switch current.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return
+	}
+	value, found = fmt.Sprint(current), true
+	return
+}
+
+func (j jsonFields) Has(field string) bool {
+	_, found := j.lookup(field)
+	return found
+}
+
+func (j jsonFields) Get(field string) string {
+	value, _ := j.lookup(field)
+	return value
+}
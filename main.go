@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"github.com/urfave/cli/v2"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func exit(err *error) {
@@ -20,27 +24,200 @@ func main() {
 	var err error
 	defer exit(&err)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Println("interrupted, cancelling, interrupt again to exit immediately")
+		cancel()
+		// restore default handling, so that a second Ctrl-C kills the process
+		signal.Stop(signals)
+	}()
+
+	concurrencyFlag := &cli.IntFlag{
+		Name:    "concurrency",
+		Aliases: []string{"c"},
+		Usage:   "number of concurrent API calls across clusters, namespaces and objects",
+		Value:   1,
+	}
+
+	excludeFlag := &cli.StringSliceFlag{
+		Name:  "exclude",
+		Usage: "skip objects with names matching pattern, a glob like 'tmp-*' or a regular expression like 're:^tmp-', can be repeated",
+	}
+	selectorFlag := &cli.StringFlag{
+		Name:    "selector",
+		Aliases: []string{"l"},
+		Usage:   "label selector like 'app=web,tier!=cache', evaluated by server on listing and against labels of local files on push",
+	}
+	fieldSelectorFlag := &cli.StringFlag{
+		Name:  "field-selector",
+		Usage: "field selector like 'metadata.name!=default', evaluated by server on listing and against fields of local files on push",
+	}
+	includeManagedFlag := &cli.BoolFlag{
+		Name:  "include-managed",
+		Usage: "include objects with ownerReferences, or labels and annotations of Helm, Kustomize and Argo CD, which are skipped by default",
+	}
+	newFilter := func(c *cli.Context) *Filter {
+		return &Filter{
+			Excludes:       c.StringSlice("exclude"),
+			Selector:       c.String("selector"),
+			FieldSelector:  c.String("field-selector"),
+			IncludeManaged: c.Bool("include-managed"),
+		}
+	}
+
 	app := cli.NewApp()
 	app.Usage = "file based kubernetes operation tool"
+	app.Before = func(c *cli.Context) error {
+		return loadConfig()
+	}
 	app.Commands = append(app.Commands, &cli.Command{
 		Name:        "pull",
 		Description: "pull resources from existing cluster",
+		Flags: []cli.Flag{
+			concurrencyFlag,
+			excludeFlag,
+			selectorFlag,
+			fieldSelectorFlag,
+			includeManagedFlag,
+			&cli.BoolFlag{
+				Name:  "minimal",
+				Usage: "strip values equal to server defaults, such as 'imagePullPolicy' or 'dnsPolicy', pushing a minimal file results in the same live object",
+			},
+			&cli.BoolFlag{
+				Name:  "headers",
+				Usage: "keep 'apiVersion' and 'kind' at the top of pulled files, they are ignored on push",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() != 4 {
 				return errors.New("invalid number of arguments")
 			}
-			return commandPull(c.Context, c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3))
+			return commandPull(c.Context, c.Int("concurrency"), c.Bool("minimal"), c.Bool("headers"), newFilter(c), c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3))
 		},
 	})
 	app.Commands = append(app.Commands, &cli.Command{
 		Name:        "push",
 		Description: "push resources to existing cluster",
+		Flags: []cli.Flag{
+			concurrencyFlag,
+			excludeFlag,
+			selectorFlag,
+			fieldSelectorFlag,
+			includeManagedFlag,
+			&cli.StringFlag{
+				Name:  "dry-run",
+				Usage: "rehearse push without persisting anything, 'client' compares with live objects only, 'server' also runs validation and admission webhooks",
+			},
+			&cli.BoolFlag{
+				Name:  "prune",
+				Usage: "delete live objects without a local file, objects annotated with '" + annotationProtected + ": \"true\"' are kept",
+			},
+			&cli.BoolFlag{
+				Name:  "server-side",
+				Usage: "use server side apply with field manager '" + fieldManager + "', fields owned by other managers are kept",
+			},
+			&cli.BoolFlag{
+				Name:  "force-conflicts",
+				Usage: "take ownership of fields conflicting with other managers, requires --server-side",
+			},
+			&cli.BoolFlag{
+				Name:  "wait",
+				Usage: "wait for deployments, statefulsets and daemonsets to roll out, fail if pods are stuck",
+			},
+			&cli.DurationFlag{
+				Name:  "wait-timeout",
+				Usage: "timeout of --wait for each workload",
+				Value: 5 * time.Minute,
+			},
+			&cli.BoolFlag{
+				Name:    "suspend",
+				Usage:   "push cronjobs suspended, so that they do not fire in a cloned environment",
+				EnvVars: []string{"KOOP_SUSPEND"},
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "do not ask for confirmation before pruning",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() != 4 {
 				return errors.New("invalid number of arguments")
 			}
-			return commandPush(c.Context, c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3))
+			opts := PushOptions{
+				DryRun:         c.String("dry-run"),
+				Prune:          c.Bool("prune"),
+				Yes:            c.Bool("yes"),
+				ServerSide:     c.Bool("server-side"),
+				ForceConflicts: c.Bool("force-conflicts"),
+				Wait:           c.Bool("wait"),
+				WaitTimeout:    c.Duration("wait-timeout"),
+				Concurrency:    c.Int("concurrency"),
+				Suspend:        c.Bool("suspend"),
+			}
+			return commandPush(c.Context, opts, newFilter(c), c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3))
+		},
+	})
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:        "diff",
+		Description: "compare local resources against existing cluster, exit non-zero if drift found",
+		Flags: []cli.Flag{
+			concurrencyFlag,
+			excludeFlag,
+			selectorFlag,
+			fieldSelectorFlag,
+			includeManagedFlag,
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 4 {
+				return errors.New("invalid number of arguments")
+			}
+			return commandDiff(c.Context, c.Int("concurrency"), newFilter(c), c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3))
+		},
+	})
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:        "sanitizers",
+		Usage:       "sanitizers [CLUSTER] [KIND]",
+		Description: "print effective sanitizers of kinds, built-in ones followed by ones from config",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return errors.New("invalid number of arguments")
+			}
+			return commandSanitizers(c.Context, c.Args().Get(0), c.Args().Get(1))
+		},
+	})
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:        "secret",
+		Description: "manage encrypted secrets in local files",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "reveal",
+				Usage:       "reveal [CLUSTER] [NAMESPACE] [NAME]",
+				Description: "print decrypted secret",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 3 {
+						return errors.New("invalid number of arguments")
+					}
+					return commandSecretReveal(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2))
+				},
+			},
+			{
+				Name:        "edit",
+				Usage:       "edit [CLUSTER] [NAMESPACE] [NAME]",
+				Description: "edit decrypted secret with $EDITOR, then save it encrypted",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 3 {
+						return errors.New("invalid number of arguments")
+					}
+					return commandSecretEdit(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2))
+				},
+			},
 		},
 	})
-	err = app.Run(os.Args)
+	err = app.RunContext(ctx, os.Args)
 }
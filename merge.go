@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"gopkg.in/yaml.v3"
+)
+
+// MergeYAML merges a freshly pulled document into an existing local file, keeping comments, key order and styles
+// of the existing file wherever keys and values still exist, new keys are placed after their predecessors in fresh order
+func MergeYAML(existing, fresh []byte) (out []byte, err error) {
+	var oldDoc, newDoc yaml.Node
+	if err = yaml.Unmarshal(existing, &oldDoc); err != nil || len(oldDoc.Content) == 0 {
+		// an unreadable or empty local file is replaced
+		out, err = fresh, nil
+		return
+	}
+	if err = yaml.Unmarshal(fresh, &newDoc); err != nil {
+		return
+	}
+	if len(newDoc.Content) == 0 {
+		out = fresh
+		return
+	}
+	oldDoc.Content[0] = mergeYAMLNode(oldDoc.Content[0], newDoc.Content[0])
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(4)
+	if err = enc.Encode(&oldDoc); err != nil {
+		return
+	}
+	if err = enc.Close(); err != nil {
+		return
+	}
+	out = buf.Bytes()
+	return
+}
+
+// mergeYAMLNode returns the fresh node, reusing the old node where the value is unchanged
+func mergeYAMLNode(old, fresh *yaml.Node) *yaml.Node {
+	if old.Kind != fresh.Kind {
+		copyYAMLComments(fresh, old)
+		return fresh
+	}
+	switch fresh.Kind {
+	case yaml.MappingNode:
+		merged := *old
+		merged.Content = nil
+		// an empty collection is written in flow style like '{}', keep the style only if there was content
+		if len(old.Content) == 0 {
+			merged.Style = fresh.Style
+		}
+		freshValues := map[string]*yaml.Node{}
+		for i := 0; i+1 < len(fresh.Content); i += 2 {
+			freshValues[fresh.Content[i].Value] = fresh.Content[i+1]
+		}
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			key := old.Content[i].Value
+			value, ok := freshValues[key]
+			if !ok {
+				continue
+			}
+			seen[key] = true
+			merged.Content = append(merged.Content, old.Content[i], mergeYAMLNode(old.Content[i+1], value))
+		}
+		// new keys go after the key preceding them in fresh order, or first, so that headers like 'apiVersion' stay on top
+		previous := ""
+		for i := 0; i+1 < len(fresh.Content); i += 2 {
+			key := fresh.Content[i].Value
+			if !seen[key] {
+				at := 0
+				for j := 0; j+1 < len(merged.Content); j += 2 {
+					if merged.Content[j].Value == previous {
+						at = j + 2
+						break
+					}
+				}
+				if at == 0 && len(merged.Content) > 0 {
+					// the head comment of a mapping stays on top
+					fresh.Content[i].HeadComment, merged.Content[0].HeadComment = merged.Content[0].HeadComment, ""
+				}
+				merged.Content = append(merged.Content[:at], append([]*yaml.Node{fresh.Content[i], fresh.Content[i+1]}, merged.Content[at:]...)...)
+				seen[key] = true
+			}
+			previous = key
+		}
+		return &merged
+	case yaml.SequenceNode:
+		merged := *old
+		merged.Content = nil
+		if len(old.Content) == 0 {
+			merged.Style = fresh.Style
+		}
+		oldByName := map[string]*yaml.Node{}
+		for _, item := range old.Content {
+			if name := yamlItemName(item); name != "" {
+				oldByName[name] = item
+			}
+		}
+		for i, item := range fresh.Content {
+			// items with names, like containers, are matched by name, others by index
+			if name := yamlItemName(item); name != "" {
+				if prev, ok := oldByName[name]; ok {
+					merged.Content = append(merged.Content, mergeYAMLNode(prev, item))
+					continue
+				}
+			} else if i < len(old.Content) && yamlItemName(old.Content[i]) == "" {
+				merged.Content = append(merged.Content, mergeYAMLNode(old.Content[i], item))
+				continue
+			}
+			merged.Content = append(merged.Content, item)
+		}
+		return &merged
+	case yaml.ScalarNode:
+		if old.Value == fresh.Value && old.ShortTag() == fresh.ShortTag() {
+			return old
+		}
+		copyYAMLComments(fresh, old)
+		return fresh
+	}
+	return fresh
+}
+
+// yamlItemName returns the 'name' of a sequence item, empty if it has none
+func yamlItemName(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+func copyYAMLComments(to, from *yaml.Node) {
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+}
@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestMergeYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		fresh    string
+		want     string
+	}{
+		{
+			name:     "unchanged values keep comments and order",
+			existing: "# config\nspec:\n    b: 1 # one\n    a: 2\nmetadata:\n    labels:\n        app: x\n",
+			fresh:    "metadata:\n    labels:\n        app: x\nspec:\n    a: 2\n    b: 1\n",
+			want:     "# config\nspec:\n    b: 1 # one\n    a: 2\nmetadata:\n    labels:\n        app: x\n",
+		},
+		{
+			name:     "changed value keeps its comment",
+			existing: "spec:\n    # scaled by hand\n    replicas: 2 # two\n",
+			fresh:    "spec:\n    replicas: 3\n",
+			want:     "spec:\n    # scaled by hand\n    replicas: 3 # two\n",
+		},
+		{
+			name:     "removed keys are dropped",
+			existing: "a: 1 # gone\nb: 2\n",
+			fresh:    "b: 2\n",
+			want:     "b: 2\n",
+		},
+		{
+			name:     "new key after its predecessor",
+			existing: "a: 1\nc: 3\n",
+			fresh:    "a: 1\nb: 2\nc: 3\n",
+			want:     "a: 1\nb: 2\nc: 3\n",
+		},
+		{
+			name:     "new key at head of mapping with head comment",
+			existing: "# managed by team a\nmetadata:\n    name: x\nspec: {}\n",
+			fresh:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: x\nspec: {}\n",
+			want:     "# managed by team a\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: x\nspec: {}\n",
+		},
+		{
+			name:     "containers matched by name",
+			existing: "containers:\n    - name: sidecar # proxy\n      image: proxy:1\n    - name: web\n      image: web:1 # pinned\n",
+			fresh:    "containers:\n    - name: web\n      image: web:2\n    - name: sidecar\n      image: proxy:1\n",
+			want:     "containers:\n    - name: web\n      image: web:2 # pinned\n    - name: sidecar # proxy\n      image: proxy:1\n",
+		},
+		{
+			name:     "renamed container replaces the old one",
+			existing: "containers:\n    # the app\n    - name: web # old name\n      image: web:1\n    - name: sidecar # proxy\n      image: proxy:1\n",
+			fresh:    "containers:\n    - name: app\n      image: web:1\n    - name: sidecar\n      image: proxy:1\n",
+			want:     "containers:\n    - name: app\n      image: web:1\n    - name: sidecar # proxy\n      image: proxy:1\n",
+		},
+		{
+			name:     "items without name matched by index",
+			existing: "args:\n    - --a # first\n    - --b\n",
+			fresh:    "args:\n    - --a\n    - --c\n    - --d\n",
+			want:     "args:\n    - --a # first\n    - --c\n    - --d\n",
+		},
+		{
+			name:     "empty flow collections gaining content use block style",
+			existing: "resources: {}\nargs: []\nlabels: {a: 1}\n",
+			fresh:    "resources:\n    limits:\n        cpu: 1\nargs:\n    - --a\nlabels:\n    a: 1\n    b: 2\n",
+			want:     "resources:\n    limits:\n        cpu: 1\nargs:\n    - --a\nlabels: {a: 1, b: 2}\n",
+		},
+		{
+			name:     "unreadable existing file is replaced",
+			existing: "a: [\n",
+			fresh:    "a: 1\n",
+			want:     "a: 1\n",
+		},
+	}
+	for _, test := range tests {
+		out, err := MergeYAML([]byte(test.existing), []byte(test.fresh))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, out, test.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sort"
+)
+
+// leadingYAMLKeys go first in any mapping, in this order, like hand-written manifests
+var leadingYAMLKeys = []string{"apiVersion", "kind", "metadata", "name", "namespace", "spec"}
+
+// OrderedJSON2YAML converts JSON to YAML with keys in field order of the Go API struct of gvk,
+// keys unknown to the struct, or of kinds unknown to client-go, follow leadingYAMLKeys then alphabetical order
+func OrderedJSON2YAML(buf []byte, gvk schema.GroupVersionKind) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(buf, &m); err != nil {
+		return
+	}
+	var node yaml.Node
+	if err = node.Encode(m); err != nil {
+		return
+	}
+	orderYAMLNode(&node, structOrderTemplate(buf, gvk))
+	w := &bytes.Buffer{}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(4)
+	if err = enc.Encode(&node); err != nil {
+		return
+	}
+	if err = enc.Close(); err != nil {
+		return
+	}
+	out = w.Bytes()
+	return
+}
+
+// structOrderTemplate round trips an object through its Go API struct, the resulting keys are in struct field order,
+// returns nil if the kind is unknown or the object does not fit
+func structOrderTemplate(buf []byte, gvk schema.GroupVersionKind) *yaml.Node {
+	if gvk.Kind == "" {
+		return nil
+	}
+	obj, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		return nil
+	}
+	if err = json.Unmarshal(buf, obj); err != nil {
+		return nil
+	}
+	if buf, err = json.Marshal(obj); err != nil {
+		return nil
+	}
+	// JSON is YAML, decoding into a node keeps the key order
+	var node yaml.Node
+	if err = yaml.Unmarshal(buf, &node); err != nil || len(node.Content) == 0 {
+		return nil
+	}
+	return node.Content[0]
+}
+
+// orderYAMLNode sorts keys of mappings recursively, by leadingYAMLKeys, then by position in template, then keeps the order
+func orderYAMLNode(node, template *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			orderYAMLNode(child, template)
+		}
+	case yaml.MappingNode:
+		templateValues := map[string]*yaml.Node{}
+		rank := map[string]int{}
+		for i, key := range leadingYAMLKeys {
+			rank[key] = i - len(leadingYAMLKeys)
+		}
+		if template != nil && template.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(template.Content); i += 2 {
+				key := template.Content[i].Value
+				templateValues[key] = template.Content[i+1]
+				if _, ok := rank[key]; !ok {
+					rank[key] = i/2 + 1
+				}
+			}
+		}
+		type pair struct {
+			key, value *yaml.Node
+		}
+		pairs := make([]pair, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, pair{key: node.Content[i], value: node.Content[i+1]})
+		}
+		// leading keys rank below zero, template keys from one, other keys go last
+		last := len(templateValues) + 1
+		keyRank := func(key string) int {
+			if r, ok := rank[key]; ok {
+				return r
+			}
+			return last
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return keyRank(pairs[i].key.Value) < keyRank(pairs[j].key.Value)
+		})
+		node.Content = node.Content[:0]
+		for _, p := range pairs {
+			orderYAMLNode(p.value, templateValues[p.key.Value])
+			node.Content = append(node.Content, p.key, p.value)
+		}
+	case yaml.SequenceNode:
+		var templateItems []*yaml.Node
+		if template != nil && template.Kind == yaml.SequenceNode {
+			templateItems = template.Content
+		}
+		byName := map[string]*yaml.Node{}
+		for _, item := range templateItems {
+			if name := yamlItemName(item); name != "" {
+				byName[name] = item
+			}
+		}
+		for i, item := range node.Content {
+			var itemTemplate *yaml.Node
+			if name := yamlItemName(item); name != "" && byName[name] != nil {
+				itemTemplate = byName[name]
+			} else if i < len(templateItems) {
+				itemTemplate = templateItems[i]
+			}
+			orderYAMLNode(item, itemTemplate)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"testing"
+)
+
+func TestOrderedJSON2YAML(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	tests := []struct {
+		name string
+		gvk  schema.GroupVersionKind
+		in   string
+		want string
+	}{
+		{
+			name: "leading keys of unknown kind",
+			in:   `{"spec": {"x": 1}, "data": {"b": 1, "a": 2}, "metadata": {"name": "a"}, "kind": "Foo", "apiVersion": "x.io/v1"}`,
+			want: "apiVersion: x.io/v1\nkind: Foo\nmetadata:\n    name: a\nspec:\n    x: 1\ndata:\n    a: 2\n    b: 1\n",
+		},
+		{
+			name: "name first in list items of unknown kind",
+			in:   `{"items": [{"value": 1, "name": "b"}, {"age": 2, "name": "a", "namespace": "n"}]}`,
+			want: "items:\n    - name: b\n      value: 1\n    - name: a\n      namespace: \"n\"\n      age: 2\n",
+		},
+		{
+			name: "struct field order of known kind",
+			gvk:  deployment,
+			in: `{"spec": {"template": {"spec": {"containers": [{"image": "nginx", "ports": [{"protocol": "TCP", "containerPort": 80, "name": "http"}], "name": "web"}]}}, "replicas": 2},` +
+				`"metadata": {"labels": {"app": "x"}, "annotations": {"a": "b"}}}`,
+			want: "metadata:\n    labels:\n        app: x\n    annotations:\n        a: b\nspec:\n    replicas: 2\n    template:\n        spec:\n" +
+				"            containers:\n                - name: web\n                  image: nginx\n                  ports:\n" +
+				"                    - name: http\n                      containerPort: 80\n                      protocol: TCP\n",
+		},
+		{
+			name: "keys unknown to struct go last",
+			gvk:  deployment,
+			in:   `{"zeta": 1, "alpha": 2, "spec": {"custom": true, "paused": true}, "metadata": {"name": "x"}}`,
+			want: "metadata:\n    name: x\nspec:\n    paused: true\n    custom: true\nalpha: 2\nzeta: 1\n",
+		},
+		{
+			name: "headers of known kind",
+			gvk:  deployment,
+			in:   `{"spec": {"paused": true}, "kind": "Deployment", "metadata": {"name": "x"}, "apiVersion": "apps/v1"}`,
+			want: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n    name: x\nspec:\n    paused: true\n",
+		},
+	}
+	for _, test := range tests {
+		out, err := OrderedJSON2YAML([]byte(test.in), test.gvk)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, out, test.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds concurrent API calls, the first failure cancels the context shared by all tasks
+type Pool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	serial bool
+
+	mu  sync.Mutex
+	err error
+}
+
+func NewPool(ctx context.Context, concurrency int) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	p := &Pool{sem: make(chan struct{}, concurrency), serial: concurrency == 1}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+func (p *Pool) Context() context.Context {
+	return p.ctx
+}
+
+func (p *Pool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+// Err returns the first failure, or the cancellation of parent context
+func (p *Pool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	return p.ctx.Err()
+}
+
+// Run calls fn holding a slot of the pool, fn must not call Run again
+func (p *Pool) Run(fn func(ctx context.Context) error) (err error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return p.Err()
+	}
+	defer func() { <-p.sem }()
+	if err = fn(p.ctx); err != nil {
+		p.fail(err)
+	}
+	return
+}
+
+// Each calls fn for every index and waits for all of them, in order if concurrency is 1; at most concurrency calls run at once,
+// they do not hold slots, since fn calls Run, which would deadlock once all slots are held by waiting callers
+func (p *Pool) Each(n int, fn func(i int) error) (err error) {
+	if p.serial {
+		for i := 0; i < n; i++ {
+			if err = fn(i); err != nil {
+				p.fail(err)
+				return
+			}
+		}
+		return
+	}
+	workers := cap(p.sem)
+	if workers > n {
+		workers = n
+	}
+	indexes := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if p.ctx.Err() != nil {
+					continue
+				}
+				if err := fn(i); err != nil {
+					p.fail(err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return p.Err()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	annotationProtected = "autoops.koop/protected"
+)
+
+type pruneCandidate struct {
+	client    *Client
+	resource  *Resource
+	cluster   string
+	namespace string
+	name      string
+}
+
+// less orders candidates in visiting order, see lessVisited
+func (c pruneCandidate) less(o pruneCandidate) bool {
+	return lessVisited(c.cluster, c.namespace, o.cluster, o.namespace)
+}
+
+// lessVisited orders by cluster, then cluster scope before namespaces, the order clusters and namespaces are visited in
+func lessVisited(cluster, namespace, otherCluster, otherNamespace string) bool {
+	if cluster != otherCluster {
+		return cluster < otherCluster
+	}
+	if (namespace == namespaceCluster) != (otherNamespace == namespaceCluster) {
+		return namespace == namespaceCluster
+	}
+	return namespace < otherNamespace
+}
+
+func (c pruneCandidate) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", c.cluster, c.namespace, c.resource.Kind, c.name)
+}
+
+func isProtected(data []byte) bool {
+	var obj struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return false
+	}
+	protected, _ := strconv.ParseBool(obj.Metadata.Annotations[annotationProtected])
+	return protected
+}
+
+// collectPruneCandidates finds live objects without a local file, kinds without a local directory are never pruned
+func collectPruneCandidates(ctx context.Context, client *Client, resource *Resource, filter *Filter, cluster, namespace, dir string, localNames []string) (candidates []pruneCandidate, err error) {
+	if _, err = os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if err = checkPruneDir(dir); err != nil {
+		return
+	}
+	local := map[string]bool{}
+	for _, name := range localNames {
+		local[name] = true
+	}
+	var objects []Object
+	if objects, err = resource.List(ctx, client, namespace, filter.ListOptions()); err != nil {
+		return
+	}
+	for _, object := range filter.Objects(cluster, resource, objects) {
+		if local[object.Name] {
+			continue
+		}
+		candidate := pruneCandidate{client: client, resource: resource, cluster: cluster, namespace: namespace, name: object.Name}
+		if isProtected(object.JSON) {
+			log.Printf("PRUNE: %s: protected by annotation %s", candidate, annotationProtected)
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return
+}
+
+// checkPruneDir refuses to prune a directory with entries other than '.yaml' files, since an object saved as '.yml' would be
+// taken as missing locally and deleted
+func checkPruneDir(dir string) (err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".yaml") {
+			err = fmt.Errorf("refusing to prune %s, found unexpected '%s', all YAML files must have extension '.yaml'", dir, info.Name())
+			return
+		}
+	}
+	return
+}
+
+func confirmPrune(count int) (ok bool, err error) {
+	fmt.Fprintf(os.Stderr, "delete %d objects listed above? [y/N] ", count)
+	var line string
+	if line, err = bufio.NewReader(os.Stdin).ReadString('\n'); err != nil && line == "" {
+		return
+	}
+	err = nil
+	line = strings.ToLower(strings.TrimSpace(line))
+	ok = line == "y" || line == "yes"
+	return
+}
+
+func executePrune(ctx context.Context, opts PushOptions, label string, candidates []pruneCandidate) (err error) {
+	if len(candidates) == 0 {
+		return
+	}
+	for _, candidate := range candidates {
+		log.Printf("PRUNE: %s", candidate)
+	}
+	if opts.DryRun == DryRunNone && !opts.Yes {
+		var ok bool
+		if ok, err = confirmPrune(len(candidates)); err != nil {
+			return
+		}
+		if !ok {
+			err = errors.New("prune aborted")
+			return
+		}
+	}
+	// candidates are collected in apply order, delete dependents first
+	for i := len(candidates) - 1; i >= 0; i-- {
+		candidate := candidates[i]
+		if opts.DryRun != DryRunClient {
+			if err = candidate.resource.Delete(ctx, candidate.client, candidate.namespace, candidate.name, opts); err != nil {
+				log.Printf("%s: %s: %s: %s", label, candidate, ResultRejected, err.Error())
+				return
+			}
+		}
+		log.Printf("%s: %s: %s", label, candidate, ResultDeleted)
+	}
+	return
+}
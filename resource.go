@@ -2,59 +2,436 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
+const (
+	DryRunNone   = ""
+	DryRunClient = "client"
+	DryRunServer = "server"
+
+	ResultCreated   = "created"
+	ResultUpdated   = "updated"
+	ResultUnchanged = "unchanged"
+	ResultRejected  = "rejected"
+	ResultSkipped   = "skipped"
+	ResultDeleted   = "deleted"
+	// ResultFailed is reported for errors other than rejections of an object, such as network or authentication errors
+	ResultFailed = "failed"
+)
+
+const (
+	fieldManager = "koop"
+	// fieldManagerZeroReplicas owns replicas of workloads created with KOOP_ZERO_REPLICAS by server side apply
+	fieldManagerZeroReplicas = "koop-zero-replicas"
+)
+
+type PushOptions struct {
+	DryRun         string
+	Prune          bool
+	Yes            bool
+	ServerSide     bool
+	ForceConflicts bool
+	Wait           bool
+	WaitTimeout    time.Duration
+	Concurrency    int
+	Suspend        bool
+}
+
+func (o PushOptions) Validate() error {
+	switch o.DryRun {
+	case DryRunNone, DryRunClient, DryRunServer:
+	default:
+		return fmt.Errorf("invalid dry run mode '%s', must be '%s' or '%s'", o.DryRun, DryRunClient, DryRunServer)
+	}
+	if o.ForceConflicts && !o.ServerSide {
+		return fmt.Errorf("force conflicts requires server side apply")
+	}
+	return nil
+}
+
+func (o PushOptions) dryRun() []string {
+	if o.DryRun == DryRunServer {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+func (o PushOptions) CreateOptions() metav1.CreateOptions {
+	return metav1.CreateOptions{DryRun: o.dryRun()}
+}
+
+func (o PushOptions) UpdateOptions() metav1.UpdateOptions {
+	return metav1.UpdateOptions{DryRun: o.dryRun()}
+}
+
+// PatchOptions returns options for server side apply
+func (o PushOptions) PatchOptions() metav1.PatchOptions {
+	force := o.ForceConflicts
+	return metav1.PatchOptions{DryRun: o.dryRun(), FieldManager: fieldManager, Force: &force}
+}
+
+// ZeroReplicasPatchOptions returns options for server side apply of replicas of workloads created with KOOP_ZERO_REPLICAS
+func (o PushOptions) ZeroReplicasPatchOptions() metav1.PatchOptions {
+	force := false
+	return metav1.PatchOptions{DryRun: o.dryRun(), FieldManager: fieldManagerZeroReplicas, Force: &force}
+}
+
+func (o PushOptions) DeleteOptions() metav1.DeleteOptions {
+	propagation := metav1.DeletePropagationBackground
+	return metav1.DeleteOptions{DryRun: o.dryRun(), PropagationPolicy: &propagation}
+}
+
+// apply order of resource kinds, dependencies go first, prune goes in reverse
+const (
+	OrderNamespace = (iota + 1) * 10
+	OrderConfig
+	OrderStorage
+	OrderService
+	OrderWorkload
+	OrderAutoscaler
+	OrderIngress
+	OrderDefault
+)
+
+// Object is a listed object in JSON
+type Object struct {
+	Name string
+	JSON []byte
+}
+
 type Resource struct {
-	Kind    string
-	List    func(ctx context.Context, client *kubernetes.Clientset, namespace string) ([]string, error)
-	GetJSON func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) ([]byte, error)
-	SetJSON func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) error
+	Kind string
+	// Order is the apply order of this kind, see Order* constants
+	Order int
+	// Group and Plural identify the API resource served by this typed resource
+	Group  string
+	Plural string
+	// GVK is the API version and kind of objects of typed resources, empty for resources of objects carrying their own
+	GVK schema.GroupVersionKind
+	// Aliases are kubectl style short names and singular names, matched along with Kind and Plural
+	Aliases []string
+	// Namespaced marks objects of this kind live in namespaces, cluster scoped objects are stored in '_cluster' directory
+	Namespaced bool
+	// Encrypted marks values in '/data' are encrypted in local files
+	Encrypted bool
+	// PodTemplate is the JSON pointer of pod template of workloads, Defaults are other server defaulted values of this kind,
+	// both are stripped from minimal files
+	PodTemplate string
+	Defaults    PatchSet
+
+	List    func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) ([]Object, error)
+	GetJSON func(ctx context.Context, client *Client, namespace, name string) ([]byte, error)
+	SetJSON func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) error
+	Delete  func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) error
+	// Prepare adjusts a local object to the cluster and push options before comparing and pushing, nil for most kinds
+	Prepare func(client *Client, data []byte, opts PushOptions) ([]byte, error)
+	// Rollout reports rollout progress of workloads, nil for other kinds
+	Rollout func(ctx context.Context, client *Client, namespace, name string) (RolloutStatus, error)
 }
 
-func (r Resource) GetCanonicalYAML(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+func (r Resource) GetCanonicalYAML(ctx context.Context, client *Client, cluster, namespace, name string) (data []byte, err error) {
 	if data, err = r.GetJSON(ctx, client, namespace, name); err != nil {
 		return
 	}
+	data, err = r.CanonicalYAML(cluster, namespace, name, data, false, false)
+	return
+}
+
+// ListTyped lists objects with apiVersion and kind, which objects of typed resources are listed without
+func (r Resource) ListTyped(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+	if objects, err = r.List(ctx, client, namespace, opts); err != nil || r.GVK.Kind == "" {
+		return
+	}
+	for i := range objects {
+		if objects[i].JSON, err = SetTypeMetaJSON(objects[i].JSON, r.GVK); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GetTypedJSON gets an object with apiVersion and kind, see ListTyped
+func (r Resource) GetTypedJSON(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+	if data, err = r.GetJSON(ctx, client, namespace, name); err != nil || len(data) == 0 || r.GVK.Kind == "" {
+		return
+	}
+	data, err = SetTypeMetaJSON(data, r.GVK)
+	return
+}
+
+// typeOf returns the API version and kind of an object, from the object itself if set, otherwise GVK of this resource
+func (r Resource) typeOf(data []byte) schema.GroupVersionKind {
+	var meta metav1.TypeMeta
+	if err := json.Unmarshal(data, &meta); err == nil && meta.APIVersion != "" && meta.Kind != "" {
+		return meta.GroupVersionKind()
+	}
+	return r.GVK
+}
+
+// Minimize removes values equal to server defaults, pushing a minimal object results in the same live object
+func (r Resource) Minimize(data []byte) (out []byte, err error) {
+	defaults := r.Defaults
+	if r.PodTemplate != "" {
+		defaults = append(append(PatchSet{}, defaults...), podTemplateDefaults(r.PodTemplate)...)
+	}
+	if out, err = defaults.Apply(data); err != nil {
+		return
+	}
+	if r.PodTemplate != "" {
+		out, err = removeDefaultImagePullPolicy(out, r.PodTemplate)
+	}
+	return
+}
+
+// CanonicalYAML converts live object namespace/name in JSON to the content of local file, keys in field order of the API struct,
+// minimal removes server defaults, headers keeps 'apiVersion' and 'kind'
+func (r Resource) CanonicalYAML(cluster, namespace, name string, data []byte, minimal bool, headers bool) (out []byte, err error) {
 	if len(data) == 0 {
 		return
 	}
-	if data, err = defaultSanitizers.Apply(data); err != nil {
+	gvk := r.typeOf(data)
+	if data, err = koopConfig.SanitizersFor(cluster, &r).Apply(data); err != nil {
 		return
 	}
-	data, err = JSON2YAML(data)
+	if minimal {
+		if data, err = r.Minimize(data); err != nil {
+			return
+		}
+	}
+	if r.Encrypted {
+		if data, err = EncryptSecretJSON(data, namespace, name); err != nil {
+			return
+		}
+	}
+	if headers && gvk.Kind != "" {
+		if data, err = SetTypeMetaJSON(data, gvk); err != nil {
+			return
+		}
+	}
+	out, err = OrderedJSON2YAML(data, gvk)
 	return
 }
 
-func (r Resource) SetCanonicalYAML(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+// NormalizeYAML sanitizes local YAML file of namespace/name and re-encodes it the same way as CanonicalYAML, keys are ordered for gvk if set,
+// so that a local file is ordered the same way as its live object, otherwise for the type of the file;
+// with a client, the file is prepared as on push, converting it to the version served by cluster like its live object
+func (r Resource) NormalizeYAML(client *Client, cluster, namespace, name string, data []byte, minimal bool, gvk schema.GroupVersionKind) (out []byte, err error) {
 	if data, err = YAML2JSON(data); err != nil {
 		return
 	}
-	if data, err = defaultSanitizers.Apply(data); err != nil {
+	if gvk.Kind == "" {
+		gvk = r.typeOf(data)
+	}
+	if data, err = koopConfig.SanitizersFor(cluster, &r).Apply(data); err != nil {
 		return
 	}
-	if err = r.SetJSON(ctx, client, namespace, name, data); err != nil {
+	if client != nil && r.Prepare != nil {
+		if data, err = r.Prepare(client, data, PushOptions{}); err != nil {
+			return
+		}
+	}
+	if minimal {
+		if data, err = r.Minimize(data); err != nil {
+			return
+		}
+	}
+	if r.Encrypted {
+		if data, err = DecryptSecretJSON(data, namespace, name); err != nil {
+			return
+		}
+		if data, err = EncryptSecretJSON(data, namespace, name); err != nil {
+			return
+		}
+	}
+	out, err = OrderedJSON2YAML(data, gvk)
+	return
+}
+
+// SetCanonicalYAML pushes a local YAML file to cluster, returns one of the Result* values; live holds objects listed in namespace
+// to compare with, objects not in live, or all if nil, are fetched; an error rejected by cluster comes with ResultRejected
+func (r Resource) SetCanonicalYAML(ctx context.Context, client *Client, cluster, namespace, name string, data []byte, live map[string][]byte, opts PushOptions) (result string, err error) {
+	sanitizers := koopConfig.SanitizersFor(cluster, &r)
+	if data, err = YAML2JSON(data); err != nil {
+		return
+	}
+	if r.Encrypted {
+		if data, err = DecryptSecretJSON(data, namespace, name); err != nil {
+			return
+		}
+	}
+	if data, err = sanitizers.Apply(data); err != nil {
+		return
+	}
+	if r.Prepare != nil {
+		if data, err = r.Prepare(client, data, opts); err != nil {
+			return
+		}
+	}
+
+	current, listed := live[name]
+	if !listed {
+		current, err = r.GetJSON(ctx, client, namespace, name)
+	}
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return
+		}
+		err = nil
+		result = ResultCreated
+	} else {
+		if len(current) == 0 {
+			result = ResultSkipped
+			return
+		}
+		if current, err = sanitizers.Apply(current); err != nil {
+			return
+		}
+		// sanitizers drop resourceVersion and other server assigned values from both sides,
+		// compare without server defaults, so that a minimal file equals its live object
+		if current, err = r.Minimize(current); err != nil {
+			return
+		}
+		var minimal []byte
+		if minimal, err = r.Minimize(data); err != nil {
+			return
+		}
+		var same bool
+		if same, err = EqualJSON(current, minimal); err != nil {
+			return
+		}
+		if same {
+			result = ResultUnchanged
+			return
+		}
+		if IsEnvNoUpdate() {
+			result = ResultSkipped
+			return
+		}
+		result = ResultUpdated
+	}
+
+	if opts.DryRun == DryRunClient {
+		return
+	}
+
+	if err = r.SetJSON(ctx, client, namespace, name, data, opts); err != nil {
+		result = ""
+		// other errors, like network or authentication failures, are not rejections of the object
+		if errors.IsInvalid(err) || errors.IsForbidden(err) {
+			result = ResultRejected
+		}
+		if opts.ServerSide {
+			err = describeApplyConflicts(err)
+		}
 		return
 	}
 	return
 }
 
+// describeApplyConflicts expands a server side apply conflict error into one line per conflicting field
+func describeApplyConflicts(err error) error {
+	status, ok := err.(errors.APIStatus)
+	if !ok || !errors.IsConflict(err) || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return err
+	}
+	sb := &strings.Builder{}
+	sb.WriteString("server side apply conflicts, use --force-conflicts to take ownership:")
+	for _, cause := range status.Status().Details.Causes {
+		sb.WriteString("\n\t")
+		sb.WriteString(cause.Field)
+		sb.WriteString(": ")
+		sb.WriteString(cause.Message)
+	}
+	return stderrors.New(sb.String())
+}
+
+// EqualJSON checks whether two JSON documents are semantically equal
+func EqualJSON(a, b []byte) (same bool, err error) {
+	var va, vb interface{}
+	if err = json.Unmarshal(a, &va); err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, &vb); err != nil {
+		return
+	}
+	same = reflect.DeepEqual(va, vb)
+	return
+}
+
 var (
 	knownResources     []*Resource
 	knownResourceNames []string
 )
 
-func findResource(kind string) (resource *Resource, err error) {
+// Matches checks whether name is the kind, plural or one of aliases of this resource, case-insensitively
+func (r Resource) Matches(name string) bool {
+	name = strings.ToLower(name)
+	if name == r.Kind || name == r.Plural {
+		return true
+	}
+	for _, alias := range r.Aliases {
+		if name == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// sortResources sorts resources by apply order, resources with same order keep their original order
+func sortResources(resources []*Resource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].Order < resources[j].Order
+	})
+}
+
+// findResource finds a registered resource by kind, plural or alias, or resolves it via API discovery to a dynamic resource;
+// a discovered resource falls back to the registered one with the same group and plural, or the same directory name
+func findResource(client *Client, kind string) (resource *Resource, err error) {
+	for _, knownResource := range knownResources {
+		if knownResource.Matches(kind) {
+			resource = knownResource
+			return
+		}
+	}
+
+	if resource, err = resolveDynamicResource(client, schema.ParseGroupResource(strings.ToLower(kind))); err != nil {
+		err = fmt.Errorf("unknown resource kind '%s', known kinds are %s: %s", kind, strings.Join(knownResourceNames, ", "), err.Error())
+		return
+	}
 	for _, knownResource := range knownResources {
-		if knownResource.Kind == kind {
+		if (knownResource.Group == resource.Group && knownResource.Plural == resource.Plural) || knownResource.Kind == resource.Kind {
 			resource = knownResource
+			return
 		}
 	}
-	if resource == nil {
-		err = fmt.Errorf("unknown resource kind '%s', known kinds are %s", kind, strings.Join(knownResourceNames, ", "))
+	return
+}
+
+// resolveDynamicResource resolves a group resource, with plural, singular or short name, to a dynamic resource of preferred version
+func resolveDynamicResource(client *Client, gr schema.GroupResource) (resource *Resource, err error) {
+	var gvr schema.GroupVersionResource
+	if gvr, err = client.Mapper.ResourceFor(gr.WithVersion("")); err != nil {
+		return
+	}
+	var gvk schema.GroupVersionKind
+	if gvk, err = client.Mapper.KindFor(gvr); err != nil {
+		return
+	}
+	var mapping *meta.RESTMapping
+	if mapping, err = client.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
 		return
 	}
+	resource = newDynamicResource(gvk, gvr, mapping.Scope.Name() == meta.RESTScopeNameNamespace)
 	return
 }
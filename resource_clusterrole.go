@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+	"strings"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "clusterrole",
+		Order:      OrderConfig,
+		Group:      "rbac.authorization.k8s.io",
+		Plural:     "clusterroles",
+		GVK:        schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		Namespaced: false,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *rbacv1.ClusterRoleList
+			if items, err = client.RbacV1().ClusterRoles().List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				// ignore objects managed by kubernetes itself
+				if strings.HasPrefix(item.Name, "system:") || item.Labels["kubernetes.io/bootstrapping"] == "rbac-defaults" {
+					continue
+				}
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *rbacv1.ClusterRole
+			if obj, err = client.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj rbacv1.ClusterRole
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "rbac.authorization.k8s.io/v1", "ClusterRole", "", name); err != nil {
+					return
+				}
+				_, err = client.RbacV1().ClusterRoles().Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *rbacv1.ClusterRole
+			if current, err = client.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+			}
+
+			if _, err = client.RbacV1().ClusterRoles().Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.RbacV1().ClusterRoles().Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.RbacV1().ClusterRoles().Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "clusterrole")
+}
@@ -6,24 +6,35 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "configmap",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:       "configmap",
+		Order:      OrderConfig,
+		Group:      "",
+		Plural:     "configmaps",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		Aliases:    []string{"cm"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *corev1.ConfigMapList
-			if items, err = client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.CoreV1().ConfigMaps(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *corev1.ConfigMap
 			if obj, err = client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
@@ -31,7 +42,7 @@ func init() {
 			data, err = json.Marshal(obj)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			var obj corev1.ConfigMap
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -39,6 +50,14 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "ConfigMap", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
 			var current *corev1.ConfigMap
 			if current, err = client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -54,10 +73,10 @@ func init() {
 				obj.ResourceVersion = current.ResourceVersion
 			}
 
-			if _, err = client.CoreV1().ConfigMaps(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.CoreV1().ConfigMaps(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
-					if _, err = client.CoreV1().ConfigMaps(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.CoreV1().ConfigMaps(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -65,6 +84,10 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().ConfigMaps(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "configmap")
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func init() {
+	// served version of CustomResourceDefinition varies, v1beta1 before kubernetes 1.16, v1 after 1.22
+	gr := schema.GroupResource{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}
+	resolve := func(client *Client) (*Resource, error) {
+		return resolveDynamicResource(client, gr)
+	}
+	knownResources = append(knownResources, &Resource{
+		Kind:       "crd",
+		Order:      OrderNamespace,
+		Group:      gr.Group,
+		Plural:     gr.Resource,
+		Aliases:    []string{"customresourcedefinition", "crds"},
+		Namespaced: false,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var resource *Resource
+			if resource, err = resolve(client); err != nil {
+				return
+			}
+			return resource.List(ctx, client, namespace, opts)
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var resource *Resource
+			if resource, err = resolve(client); err != nil {
+				return
+			}
+			return resource.GetJSON(ctx, client, namespace, name)
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var resource *Resource
+			if resource, err = resolve(client); err != nil {
+				return
+			}
+			return resource.SetJSON(ctx, client, namespace, name, data, opts)
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			var resource *Resource
+			if resource, err = resolve(client); err != nil {
+				return
+			}
+			return resource.Delete(ctx, client, namespace, name, opts)
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "crd")
+}
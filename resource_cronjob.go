@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+// batch/v1 CronJob is served since kubernetes 1.21, batch/v1beta1 is removed in 1.25
+var cronJobGVK = schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}
+
+// cronJobV1beta1 is the typed cronjob resource for clusters not serving batch/v1
+var cronJobV1beta1 = &Resource{
+	Kind:       "cronjob",
+	Order:      OrderWorkload,
+	Group:      cronJobGVK.Group,
+	Plural:     "cronjobs",
+	GVK:        schema.GroupVersionKind{Group: cronJobGVK.Group, Version: "v1beta1", Kind: cronJobGVK.Kind},
+	Namespaced: true,
+	List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+		var items *batchv1beta1.CronJobList
+		if items, err = client.BatchV1beta1().CronJobs(namespace).List(ctx, opts); err != nil {
+			return
+		}
+		for _, item := range items.Items {
+			var data []byte
+			if data, err = json.Marshal(item); err != nil {
+				return
+			}
+			objects = append(objects, Object{Name: item.Name, JSON: data})
+		}
+		return
+	},
+	GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+		var obj *batchv1beta1.CronJob
+		if obj, err = client.BatchV1beta1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return
+		}
+		data, err = json.Marshal(obj)
+		return
+	},
+	SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+		var obj batchv1beta1.CronJob
+		if err = json.Unmarshal(data, &obj); err != nil {
+			return
+		}
+		obj.Namespace = namespace
+		obj.Name = name
+
+		if opts.ServerSide {
+			if data, err = ApplyJSON(data, "batch/v1beta1", "CronJob", namespace, name); err != nil {
+				return
+			}
+			_, err = client.BatchV1beta1().CronJobs(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+			return
+		}
+
+		var current *batchv1beta1.CronJob
+		if current, err = client.BatchV1beta1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				err = nil
+			} else {
+				return
+			}
+		} else {
+			if IsEnvNoUpdate() {
+				log.Println("SKIP")
+				return
+			}
+			obj.ResourceVersion = current.ResourceVersion
+		}
+
+		if _, err = client.BatchV1beta1().CronJobs(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+			if errors.IsNotFound(err) {
+				obj.ResourceVersion = ""
+				if _, err = client.BatchV1beta1().CronJobs(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+					return
+				}
+			}
+			return
+		}
+		return
+	},
+	Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+		err = client.BatchV1beta1().CronJobs(namespace).Delete(ctx, name, opts.DeleteOptions())
+		return
+	},
+}
+
+// resolveCronJobResource prefers batch/v1 via dynamic client if served, otherwise batch/v1beta1
+func resolveCronJobResource(client *Client) *Resource {
+	if mapping, err := client.Mapper.RESTMapping(cronJobGVK.GroupKind(), cronJobGVK.Version); err == nil {
+		resource := newDynamicResource(cronJobGVK, mapping.Resource, true)
+		resource.Kind = cronJobV1beta1.Kind
+		return resource
+	}
+	return cronJobV1beta1
+}
+
+// suspendCronJob sets '/spec/suspend' to true
+func suspendCronJob(data []byte) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	spec, ok := m["spec"].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+		m["spec"] = spec
+	}
+	spec["suspend"] = true
+	out, err = json.Marshal(m)
+	return
+}
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:        cronJobV1beta1.Kind,
+		Order:       cronJobV1beta1.Order,
+		Group:       cronJobV1beta1.Group,
+		Plural:      cronJobV1beta1.Plural,
+		Aliases:     []string{"cj"},
+		Namespaced:  true,
+		PodTemplate: "/spec/jobTemplate/spec/template",
+		Defaults: PatchSet{
+			removeDefault("/spec/concurrencyPolicy", "Allow"),
+			removeDefault("/spec/suspend", false),
+			removeDefault("/spec/successfulJobsHistoryLimit", 3),
+			removeDefault("/spec/failedJobsHistoryLimit", 1),
+			removeDefault("/spec/jobTemplate/spec/backoffLimit", 6),
+		},
+		Prepare: func(client *Client, data []byte, opts PushOptions) ([]byte, error) {
+			if opts.Suspend {
+				return suspendCronJob(data)
+			}
+			return data, nil
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) ([]Object, error) {
+			return resolveCronJobResource(client).ListTyped(ctx, client, namespace, opts)
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) ([]byte, error) {
+			return resolveCronJobResource(client).GetTypedJSON(ctx, client, namespace, name)
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) error {
+			return resolveCronJobResource(client).SetJSON(ctx, client, namespace, name, data, opts)
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) error {
+			return resolveCronJobResource(client).Delete(ctx, client, namespace, name, opts)
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "cronjob")
+}
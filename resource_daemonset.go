@@ -3,27 +3,52 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	appv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "daemonset",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:        "daemonset",
+		Order:       OrderWorkload,
+		Group:       "apps",
+		Plural:      "daemonsets",
+		GVK:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		Aliases:     []string{"ds"},
+		Namespaced:  true,
+		PodTemplate: "/spec/template",
+		Defaults: PatchSet{
+			removeDefault("/spec/revisionHistoryLimit", 10),
+			removeDefault("/spec/updateStrategy", map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"maxUnavailable": 1},
+			}),
+			// maxSurge is defaulted since kubernetes 1.21
+			removeDefault("/spec/updateStrategy", map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"maxUnavailable": 1, "maxSurge": 0},
+			}),
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *appv1.DaemonSetList
-			if items, err = client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.AppsV1().DaemonSets(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *appv1.DaemonSet
 			if obj, err = client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
@@ -31,7 +56,7 @@ func init() {
 			data, err = json.Marshal(obj)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			var obj appv1.DaemonSet
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -39,6 +64,14 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "apps/v1", "DaemonSet", namespace, name); err != nil {
+					return
+				}
+				_, err = client.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
 			var current *appv1.DaemonSet
 			if current, err = client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -54,10 +87,10 @@ func init() {
 				obj.ResourceVersion = current.ResourceVersion
 			}
 
-			if _, err = client.AppsV1().DaemonSets(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.AppsV1().DaemonSets(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
-					if _, err = client.AppsV1().DaemonSets(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.AppsV1().DaemonSets(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -65,6 +98,33 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.AppsV1().DaemonSets(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+		Rollout: func(ctx context.Context, client *Client, namespace, name string) (status RolloutStatus, err error) {
+			var obj *appv1.DaemonSet
+			if obj, err = client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			if status.Selector, err = daemonSetPodSelector(ctx, client, obj); err != nil {
+				return
+			}
+			switch {
+			case obj.Status.ObservedGeneration < obj.Generation:
+				status.Message = "waiting for daemonset spec update to be observed"
+			case obj.Spec.UpdateStrategy.Type == appv1.OnDeleteDaemonSetStrategyType:
+				// pods are only updated when deleted manually
+				status.Done = true
+			case obj.Status.UpdatedNumberScheduled < obj.Status.DesiredNumberScheduled:
+				status.Message = fmt.Sprintf("%d of %d pods updated", obj.Status.UpdatedNumberScheduled, obj.Status.DesiredNumberScheduled)
+			case obj.Status.NumberAvailable < obj.Status.DesiredNumberScheduled:
+				status.Message = fmt.Sprintf("%d of %d updated pods available", obj.Status.NumberAvailable, obj.Status.DesiredNumberScheduled)
+			default:
+				status.Done = true
+			}
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "daemonset")
 }
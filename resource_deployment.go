@@ -3,27 +3,48 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	appv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "deployment",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:        "deployment",
+		Order:       OrderWorkload,
+		Group:       "apps",
+		Plural:      "deployments",
+		GVK:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Aliases:     []string{"deploy"},
+		Namespaced:  true,
+		PodTemplate: "/spec/template",
+		Defaults: PatchSet{
+			removeDefault("/spec/revisionHistoryLimit", 10),
+			removeDefault("/spec/progressDeadlineSeconds", 600),
+			removeDefault("/spec/strategy", map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": "25%"},
+			}),
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *appv1.DeploymentList
-			if items, err = client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.AppsV1().Deployments(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *appv1.Deployment
 			if obj, err = client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
@@ -31,7 +52,7 @@ func init() {
 			data, err = json.Marshal(obj)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			var obj appv1.Deployment
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -39,6 +60,37 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				var zero bool
+				if IsEnvZeroReplicas() {
+					if _, err = client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{}); errors.IsNotFound(err) {
+						zero, err = true, nil
+					} else if err != nil {
+						return
+					}
+				}
+				if data, err = ApplyJSON(data, "apps/v1", "Deployment", namespace, name); err != nil {
+					return
+				}
+				if zero {
+					if data, err = ZeroReplicasJSON(data); err != nil {
+						return
+					}
+				}
+				if _, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions()); err != nil || !zero || opts.DryRun == DryRunServer {
+					return
+				}
+				// replicas is also applied by another manager, which keeps it at 0 once later pushes leave it out
+				if data, err = ZeroReplicasJSON(nil); err != nil {
+					return
+				}
+				if data, err = ApplyJSON(data, "apps/v1", "Deployment", namespace, name); err != nil {
+					return
+				}
+				_, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.ZeroReplicasPatchOptions())
+				return
+			}
+
 			var current *appv1.Deployment
 			if current, err = client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -55,13 +107,13 @@ func init() {
 				obj.Spec.Replicas = current.Spec.Replicas
 			}
 
-			if _, err = client.AppsV1().Deployments(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.AppsV1().Deployments(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
 					if IsEnvZeroReplicas() {
 						obj.Spec.Replicas = &int32Zero
 					}
-					if _, err = client.AppsV1().Deployments(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.AppsV1().Deployments(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -69,6 +121,36 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.AppsV1().Deployments(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+		Rollout: func(ctx context.Context, client *Client, namespace, name string) (status RolloutStatus, err error) {
+			var obj *appv1.Deployment
+			if obj, err = client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			if status.Selector, err = deploymentPodSelector(ctx, client, obj); err != nil {
+				return
+			}
+			replicas := int32(1)
+			if obj.Spec.Replicas != nil {
+				replicas = *obj.Spec.Replicas
+			}
+			switch {
+			case obj.Status.ObservedGeneration < obj.Generation:
+				status.Message = "waiting for deployment spec update to be observed"
+			case obj.Status.UpdatedReplicas < replicas:
+				status.Message = fmt.Sprintf("%d of %d replicas updated", obj.Status.UpdatedReplicas, replicas)
+			case obj.Status.Replicas > obj.Status.UpdatedReplicas:
+				status.Message = fmt.Sprintf("%d old replicas pending termination", obj.Status.Replicas-obj.Status.UpdatedReplicas)
+			case obj.Status.AvailableReplicas < obj.Status.UpdatedReplicas:
+				status.Message = fmt.Sprintf("%d of %d updated replicas available", obj.Status.AvailableReplicas, obj.Status.UpdatedReplicas)
+			default:
+				status.Done = true
+			}
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "deployment")
 }
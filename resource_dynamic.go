@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"log"
+	"strings"
+)
+
+// dynamicResourceKind returns the directory name of a discovered kind, objects of built-in API groups use lower case kind,
+// others are qualified with group, for example 'cronjob', 'certificate.cert-manager.io'
+func dynamicResourceKind(gvk schema.GroupVersionKind) string {
+	kind := strings.ToLower(gvk.Kind)
+	if !strings.Contains(gvk.Group, ".") || strings.HasSuffix(gvk.Group, ".k8s.io") {
+		return kind
+	}
+	return kind + "." + gvk.Group
+}
+
+func newDynamicResource(gvk schema.GroupVersionKind, gvr schema.GroupVersionResource, namespaced bool) *Resource {
+	// namespace is ignored for cluster scoped resources
+	ri := func(client *Client, namespace string) dynamic.ResourceInterface {
+		if namespaced {
+			return client.Dynamic.Resource(gvr).Namespace(namespace)
+		}
+		return client.Dynamic.Resource(gvr)
+	}
+	return &Resource{
+		Kind:       dynamicResourceKind(gvk),
+		Order:      OrderDefault,
+		Group:      gvr.Group,
+		Plural:     gvr.Resource,
+		Namespaced: namespaced,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *unstructured.UnstructuredList
+			if items, err = ri(client, namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				var data []byte
+				if data, err = item.MarshalJSON(); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.GetName(), JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *unstructured.Unstructured
+			if obj, err = ri(client, namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			data, err = obj.MarshalJSON()
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			obj := &unstructured.Unstructured{}
+			if err = json.Unmarshal(data, &obj.Object); err != nil {
+				return
+			}
+			obj.SetGroupVersionKind(gvk)
+			if namespaced {
+				obj.SetNamespace(namespace)
+			}
+			obj.SetName(name)
+
+			if opts.ServerSide {
+				obj.SetResourceVersion("")
+				if data, err = obj.MarshalJSON(); err != nil {
+					return
+				}
+				_, err = ri(client, namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *unstructured.Unstructured
+			if current, err = ri(client, namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.SetResourceVersion(current.GetResourceVersion())
+			}
+
+			if _, err = ri(client, namespace).Update(ctx, obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.SetResourceVersion("")
+					if _, err = ri(client, namespace).Create(ctx, obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = ri(client, namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	}
+}
@@ -3,67 +3,269 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
-func init() {
-	knownResources = append(knownResources, &Resource{
-		Kind: "hpa",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
-			var items *autoscalingv2beta2.HorizontalPodAutoscalerList
-			if items, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+const (
+	hpaV1 = "autoscaling/v1"
+	hpaV2 = "autoscaling/v2"
+)
+
+// autoscaling/v2 is served since kubernetes 1.23, autoscaling/v2beta2 shares the same shape and is removed in 1.26
+var hpaGVK = schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+
+// hpaAutoscalingV2beta2 is the typed hpa resource for clusters serving autoscaling/v2beta2 but not autoscaling/v2
+var hpaAutoscalingV2beta2 = &Resource{
+	Kind:       "hpa",
+	Order:      OrderAutoscaler,
+	Group:      "autoscaling",
+	Plural:     "horizontalpodautoscalers",
+	GVK:        schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+	Namespaced: true,
+	List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+		var items *autoscalingv2beta2.HorizontalPodAutoscalerList
+		if items, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(ctx, opts); err != nil {
+			return
+		}
+		for _, item := range items.Items {
+			var data []byte
+			if data, err = json.Marshal(item); err != nil {
 				return
 			}
-			for _, item := range items.Items {
-				names = append(names, item.Name)
-			}
+			objects = append(objects, Object{Name: item.Name, JSON: data})
+		}
+		return
+	},
+	GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+		var obj *autoscalingv2beta2.HorizontalPodAutoscaler
+		if obj, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 			return
-		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
-			var obj *autoscalingv2beta2.HorizontalPodAutoscaler
-			if obj, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		}
+		data, err = json.Marshal(obj)
+		return
+	},
+	SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+		var obj autoscalingv2beta2.HorizontalPodAutoscaler
+		if err = json.Unmarshal(data, &obj); err != nil {
+			return
+		}
+		obj.Namespace = namespace
+		obj.Name = name
+
+		if opts.ServerSide {
+			if data, err = ApplyJSON(data, "autoscaling/v2beta2", "HorizontalPodAutoscaler", namespace, name); err != nil {
 				return
 			}
-			data, err = json.Marshal(obj)
+			_, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
 			return
-		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
-			var obj autoscalingv2beta2.HorizontalPodAutoscaler
-			if err = json.Unmarshal(data, &obj); err != nil {
+		}
+
+		var current *autoscalingv2beta2.HorizontalPodAutoscaler
+		if current, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				err = nil
+			} else {
 				return
 			}
-			obj.Namespace = namespace
-			obj.Name = name
+		} else {
+			if IsEnvNoUpdate() {
+				log.Println("SKIP")
+				return
+			}
+			obj.ResourceVersion = current.ResourceVersion
+		}
 
-			var current *autoscalingv2beta2.HorizontalPodAutoscaler
-			if current, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
-				if errors.IsNotFound(err) {
-					err = nil
-				} else {
+		if _, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+			if errors.IsNotFound(err) {
+				obj.ResourceVersion = ""
+				if _, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 					return
 				}
+			}
+			return
+		}
+		return
+	},
+	Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+		err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, opts.DeleteOptions())
+		return
+	},
+}
+
+// hpaAutoscalingV1 is the typed hpa resource for clusters serving autoscaling/v1 only
+var hpaAutoscalingV1 = &Resource{
+	Kind:       "hpa",
+	Order:      OrderAutoscaler,
+	Group:      "autoscaling",
+	Plural:     "horizontalpodautoscalers",
+	GVK:        schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"},
+	Namespaced: true,
+	List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+		var items *autoscalingv1.HorizontalPodAutoscalerList
+		if items, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, opts); err != nil {
+			return
+		}
+		for _, item := range items.Items {
+			var data []byte
+			if data, err = json.Marshal(item); err != nil {
+				return
+			}
+			objects = append(objects, Object{Name: item.Name, JSON: data})
+		}
+		return
+	},
+	GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+		var obj *autoscalingv1.HorizontalPodAutoscaler
+		if obj, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return
+		}
+		data, err = json.Marshal(obj)
+		return
+	},
+	SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+		var obj autoscalingv1.HorizontalPodAutoscaler
+		if err = json.Unmarshal(data, &obj); err != nil {
+			return
+		}
+		obj.Namespace = namespace
+		obj.Name = name
+
+		if opts.ServerSide {
+			if data, err = ApplyJSON(data, "autoscaling/v1", "HorizontalPodAutoscaler", namespace, name); err != nil {
+				return
+			}
+			_, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+			return
+		}
+
+		var current *autoscalingv1.HorizontalPodAutoscaler
+		if current, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				err = nil
 			} else {
-				if IsEnvNoUpdate() {
-					log.Println("SKIP")
+				return
+			}
+		} else {
+			if IsEnvNoUpdate() {
+				log.Println("SKIP")
+				return
+			}
+			obj.ResourceVersion = current.ResourceVersion
+		}
+
+		if _, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+			if errors.IsNotFound(err) {
+				obj.ResourceVersion = ""
+				if _, err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 					return
 				}
-				obj.ResourceVersion = current.ResourceVersion
 			}
+			return
+		}
+		return
+	},
+	Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+		err = client.AutoscalingV1().HorizontalPodAutoscalers(namespace).Delete(ctx, name, opts.DeleteOptions())
+		return
+	},
+}
 
-			if _, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
-				if errors.IsNotFound(err) {
-					obj.ResourceVersion = ""
-					if _, err = client.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
-						return
-					}
-				}
-				return
+// resolveHPAResource resolves the best HPA version served by cluster, returns the resource and the shape of its objects,
+// either hpaV2 or hpaV1
+func resolveHPAResource(client *Client) (*Resource, string) {
+	if mapping, err := client.Mapper.RESTMapping(hpaGVK.GroupKind(), hpaGVK.Version); err == nil {
+		resource := newDynamicResource(hpaGVK, mapping.Resource, true)
+		resource.Kind = hpaAutoscalingV2beta2.Kind
+		return resource, hpaV2
+	}
+	if _, err := client.Mapper.RESTMapping(hpaGVK.GroupKind(), "v2beta2"); err == nil {
+		return hpaAutoscalingV2beta2, hpaV2
+	}
+	return hpaAutoscalingV1, hpaV1
+}
+
+// ConvertHPAJSON converts a hpa in either shape to shape, hpaV2 or hpaV1;
+// a v2 spec converts to v1 only if it has no behavior and at most a cpu utilization metric
+func ConvertHPAJSON(data []byte, shape string) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	spec, _ := m["spec"].(map[string]interface{})
+	if spec == nil {
+		out = data
+		return
+	}
+	if shape == hpaV2 {
+		if target, ok := spec["targetCPUUtilizationPercentage"]; ok {
+			delete(spec, "targetCPUUtilizationPercentage")
+			spec["metrics"] = []interface{}{
+				map[string]interface{}{
+					"type": "Resource",
+					"resource": map[string]interface{}{
+						"name":   "cpu",
+						"target": map[string]interface{}{"type": "Utilization", "averageUtilization": target},
+					},
+				},
 			}
+		}
+	} else {
+		if _, ok := spec["behavior"]; ok {
+			err = fmt.Errorf("hpa spec.behavior can not be expressed in %s, which is the only version served by cluster", hpaV1)
 			return
+		}
+		if metrics, ok := spec["metrics"].([]interface{}); ok {
+			delete(spec, "metrics")
+			for _, metric := range metrics {
+				metric, _ := metric.(map[string]interface{})
+				resource, _ := metric["resource"].(map[string]interface{})
+				target, _ := resource["target"].(map[string]interface{})
+				if len(metrics) > 1 || metric["type"] != "Resource" || resource["name"] != "cpu" || target["type"] != "Utilization" {
+					err = fmt.Errorf("hpa spec.metrics other than a single cpu utilization can not be expressed in %s, which is the only version served by cluster", hpaV1)
+					return
+				}
+				spec["targetCPUUtilizationPercentage"] = target["averageUtilization"]
+			}
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       hpaAutoscalingV2beta2.Kind,
+		Order:      hpaAutoscalingV2beta2.Order,
+		Group:      hpaAutoscalingV2beta2.Group,
+		Plural:     hpaAutoscalingV2beta2.Plural,
+		Aliases:    []string{"horizontalpodautoscaler"},
+		Namespaced: true,
+		Prepare: func(client *Client, data []byte, opts PushOptions) ([]byte, error) {
+			_, shape := resolveHPAResource(client)
+			return ConvertHPAJSON(data, shape)
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) ([]Object, error) {
+			resource, _ := resolveHPAResource(client)
+			return resource.ListTyped(ctx, client, namespace, opts)
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) ([]byte, error) {
+			resource, _ := resolveHPAResource(client)
+			return resource.GetTypedJSON(ctx, client, namespace, name)
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) error {
+			resource, _ := resolveHPAResource(client)
+			return resource.SetJSON(ctx, client, namespace, name, data, opts)
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) error {
+			resource, _ := resolveHPAResource(client)
+			return resource.Delete(ctx, client, namespace, name, opts)
 		},
 	})
 	knownResourceNames = append(knownResourceNames, "hpa")
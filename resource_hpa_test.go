@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestConvertHPAJSON(t *testing.T) {
+	cpu := `{"type": "Resource", "resource": {"name": "cpu", "target": {"type": "Utilization", "averageUtilization": 80}}}`
+	memory := `{"type": "Resource", "resource": {"name": "memory", "target": {"type": "Utilization", "averageUtilization": 80}}}`
+	tests := []struct {
+		name  string
+		shape string
+		in    string
+		want  string
+		fail  bool
+	}{
+		{
+			name:  "v1 to v2",
+			shape: hpaV2,
+			in:    `{"spec": {"maxReplicas": 3, "targetCPUUtilizationPercentage": 80}}`,
+			want:  `{"spec": {"maxReplicas": 3, "metrics": [` + cpu + `]}}`,
+		},
+		{
+			name:  "v2 to v1 with cpu utilization",
+			shape: hpaV1,
+			in:    `{"spec": {"maxReplicas": 3, "metrics": [` + cpu + `]}}`,
+			want:  `{"spec": {"maxReplicas": 3, "targetCPUUtilizationPercentage": 80}}`,
+		},
+		{
+			name:  "v2 kept as is",
+			shape: hpaV2,
+			in:    `{"spec": {"maxReplicas": 3, "metrics": [` + memory + `]}}`,
+			want:  `{"spec": {"maxReplicas": 3, "metrics": [` + memory + `]}}`,
+		},
+		{
+			name:  "memory rejected by v1",
+			shape: hpaV1,
+			in:    `{"spec": {"maxReplicas": 3, "metrics": [` + memory + `]}}`,
+			fail:  true,
+		},
+		{
+			name:  "multiple metrics rejected by v1",
+			shape: hpaV1,
+			in:    `{"spec": {"maxReplicas": 3, "metrics": [` + cpu + `, ` + memory + `]}}`,
+			fail:  true,
+		},
+		{
+			name:  "behavior rejected by v1",
+			shape: hpaV1,
+			in:    `{"spec": {"maxReplicas": 3, "metrics": [` + cpu + `], "behavior": {"scaleDown": {"stabilizationWindowSeconds": 60}}}}`,
+			fail:  true,
+		},
+	}
+	for _, test := range tests {
+		out, err := ConvertHPAJSON([]byte(test.in), test.shape)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: got %s, want error", test.name, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		same, err := EqualJSON(out, []byte(test.want))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if !same {
+			t.Errorf("%s: got %s, want %s", test.name, out, test.want)
+		}
+	}
+}
@@ -6,64 +6,224 @@ import (
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
-func init() {
-	knownResources = append(knownResources, &Resource{
-		Kind: "ingress",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
-			var items *extensionsv1beta1.IngressList
-			if items, err = client.ExtensionsV1beta1().Ingresses(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+const (
+	ingressV1      = "networking.k8s.io/v1"
+	ingressV1beta1 = "extensions/v1beta1"
+)
+
+// preferred versions of Ingress served via dynamic client, networking.k8s.io/v1 is served since kubernetes 1.19,
+// networking.k8s.io/v1beta1 and extensions/v1beta1 share the same shape and are removed in 1.22
+var ingressGVKs = []schema.GroupVersionKind{
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"},
+}
+
+// ingressExtensionsV1beta1 is the typed ingress resource for clusters serving neither of ingressGVKs
+var ingressExtensionsV1beta1 = &Resource{
+	Kind:       "ingress",
+	Order:      OrderIngress,
+	Group:      "extensions",
+	Plural:     "ingresses",
+	GVK:        schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+	Namespaced: true,
+	List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+		var items *extensionsv1beta1.IngressList
+		if items, err = client.ExtensionsV1beta1().Ingresses(namespace).List(ctx, opts); err != nil {
+			return
+		}
+		for _, item := range items.Items {
+			var data []byte
+			if data, err = json.Marshal(item); err != nil {
 				return
 			}
-			for _, item := range items.Items {
-				names = append(names, item.Name)
-			}
+			objects = append(objects, Object{Name: item.Name, JSON: data})
+		}
+		return
+	},
+	GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+		var obj *extensionsv1beta1.Ingress
+		if obj, err = client.ExtensionsV1beta1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 			return
-		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
-			var obj *extensionsv1beta1.Ingress
-			if obj, err = client.ExtensionsV1beta1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		}
+		data, err = json.Marshal(obj)
+		return
+	},
+	SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+		var obj extensionsv1beta1.Ingress
+		if err = json.Unmarshal(data, &obj); err != nil {
+			return
+		}
+		obj.Namespace = namespace
+		obj.Name = name
+
+		if opts.ServerSide {
+			if data, err = ApplyJSON(data, "extensions/v1beta1", "Ingress", namespace, name); err != nil {
 				return
 			}
-			data, err = json.Marshal(obj)
+			_, err = client.ExtensionsV1beta1().Ingresses(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
 			return
-		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
-			var obj extensionsv1beta1.Ingress
-			if err = json.Unmarshal(data, &obj); err != nil {
+		}
+
+		var current *extensionsv1beta1.Ingress
+		if current, err = client.ExtensionsV1beta1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				err = nil
+			} else {
 				return
 			}
-			obj.Namespace = namespace
-			obj.Name = name
+		} else {
+			if IsEnvNoUpdate() {
+				log.Println("SKIP")
+				return
+			}
+			obj.ResourceVersion = current.ResourceVersion
+		}
 
-			var current *extensionsv1beta1.Ingress
-			if current, err = client.ExtensionsV1beta1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
-				if errors.IsNotFound(err) {
-					err = nil
-				} else {
-					return
-				}
-			} else {
-				if IsEnvNoUpdate() {
-					log.Println("SKIP")
+		if _, err = client.ExtensionsV1beta1().Ingresses(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+			if errors.IsNotFound(err) {
+				obj.ResourceVersion = ""
+				if _, err = client.ExtensionsV1beta1().Ingresses(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 					return
 				}
-				obj.ResourceVersion = current.ResourceVersion
 			}
+			return
+		}
+		return
+	},
+	Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+		err = client.ExtensionsV1beta1().Ingresses(namespace).Delete(ctx, name, opts.DeleteOptions())
+		return
+	},
+}
 
-			if _, err = client.ExtensionsV1beta1().Ingresses(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
-				if errors.IsNotFound(err) {
-					obj.ResourceVersion = ""
-					if _, err = client.ExtensionsV1beta1().Ingresses(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
-						return
-					}
-				}
-				return
+// resolveIngressResource resolves the newest Ingress version served by cluster, returns the resource and the shape of its objects,
+// either ingressV1 or ingressV1beta1
+func resolveIngressResource(client *Client) (*Resource, string) {
+	for _, gvk := range ingressGVKs {
+		if mapping, err := client.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			resource := newDynamicResource(gvk, mapping.Resource, true)
+			resource.Kind = ingressExtensionsV1beta1.Kind
+			if gvk.Version == "v1" {
+				return resource, ingressV1
 			}
+			return resource, ingressV1beta1
+		}
+	}
+	return ingressExtensionsV1beta1, ingressV1beta1
+}
+
+// convertIngressBackend converts a backend between 'serviceName/servicePort' and 'service.name/port', resource backends are kept
+func convertIngressBackend(backend map[string]interface{}, shape string) {
+	if shape == ingressV1 {
+		name, ok := backend["serviceName"]
+		if !ok {
 			return
+		}
+		port := map[string]interface{}{}
+		switch value := backend["servicePort"].(type) {
+		case string:
+			port["name"] = value
+		case nil:
+		default:
+			port["number"] = value
+		}
+		delete(backend, "serviceName")
+		delete(backend, "servicePort")
+		backend["service"] = map[string]interface{}{"name": name, "port": port}
+		return
+	}
+	service, ok := backend["service"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(backend, "service")
+	backend["serviceName"] = service["name"]
+	if port, ok := service["port"].(map[string]interface{}); ok {
+		if number, ok := port["number"]; ok {
+			backend["servicePort"] = number
+		} else if name, ok := port["name"]; ok {
+			backend["servicePort"] = name
+		}
+	}
+}
+
+// ConvertIngressJSON converts an ingress in either shape to shape, ingressV1 or ingressV1beta1;
+// default backend is renamed, backends are converted and missing 'pathType' is defaulted to 'ImplementationSpecific' for ingressV1
+func ConvertIngressJSON(data []byte, shape string) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	spec, _ := m["spec"].(map[string]interface{})
+	if spec == nil {
+		out = data
+		return
+	}
+	from, to := "defaultBackend", "backend"
+	if shape == ingressV1 {
+		from, to = to, from
+	}
+	if backend, ok := spec[from]; ok {
+		delete(spec, from)
+		spec[to] = backend
+	}
+	if backend, ok := spec[to].(map[string]interface{}); ok {
+		convertIngressBackend(backend, shape)
+	}
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		rule, _ := rule.(map[string]interface{})
+		http, _ := rule["http"].(map[string]interface{})
+		paths, _ := http["paths"].([]interface{})
+		for _, path := range paths {
+			path, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				convertIngressBackend(backend, shape)
+			}
+			if _, ok := path["pathType"]; !ok && shape == ingressV1 {
+				path["pathType"] = "ImplementationSpecific"
+			}
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       ingressExtensionsV1beta1.Kind,
+		Order:      ingressExtensionsV1beta1.Order,
+		Group:      "networking.k8s.io",
+		Plural:     ingressExtensionsV1beta1.Plural,
+		Aliases:    []string{"ing"},
+		Namespaced: true,
+		Prepare: func(client *Client, data []byte, opts PushOptions) ([]byte, error) {
+			_, shape := resolveIngressResource(client)
+			return ConvertIngressJSON(data, shape)
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) ([]Object, error) {
+			resource, _ := resolveIngressResource(client)
+			return resource.ListTyped(ctx, client, namespace, opts)
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) ([]byte, error) {
+			resource, _ := resolveIngressResource(client)
+			return resource.GetTypedJSON(ctx, client, namespace, name)
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) error {
+			resource, _ := resolveIngressResource(client)
+			return resource.SetJSON(ctx, client, namespace, name, data, opts)
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) error {
+			resource, _ := resolveIngressResource(client)
+			return resource.Delete(ctx, client, namespace, name, opts)
 		},
 	})
 	knownResourceNames = append(knownResourceNames, "ingress")
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestConvertIngressJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		shape     string
+		in        string
+		want      string
+		roundTrip bool
+	}{
+		{
+			name:  "v1beta1 to v1 with numeric port",
+			shape: ingressV1,
+			in:    `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "pathType": "Prefix", "backend": {"serviceName": "web", "servicePort": 80}}]}}]}}`,
+			want:  `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "pathType": "Prefix", "backend": {"service": {"name": "web", "port": {"number": 80}}}}]}}]}}`,
+		},
+		{
+			name:  "v1beta1 to v1 with named port",
+			shape: ingressV1,
+			in:    `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "pathType": "Prefix", "backend": {"serviceName": "web", "servicePort": "http"}}]}}]}}`,
+			want:  `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "pathType": "Prefix", "backend": {"service": {"name": "web", "port": {"name": "http"}}}}]}}]}}`,
+		},
+		{
+			name:  "backend to defaultBackend",
+			shape: ingressV1,
+			in:    `{"spec": {"backend": {"serviceName": "web", "servicePort": 80}}}`,
+			want:  `{"spec": {"defaultBackend": {"service": {"name": "web", "port": {"number": 80}}}}}`,
+		},
+		{
+			name:  "missing pathType defaults for v1",
+			shape: ingressV1,
+			in:    `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "backend": {"serviceName": "web", "servicePort": 80}}]}}]}}`,
+			want:  `{"spec": {"rules": [{"http": {"paths": [{"path": "/", "pathType": "ImplementationSpecific", "backend": {"service": {"name": "web", "port": {"number": 80}}}}]}}]}}`,
+		},
+		{
+			name:  "v1 to v1beta1 and back",
+			shape: ingressV1beta1,
+			in: `{"spec": {"defaultBackend": {"service": {"name": "web", "port": {"name": "http"}}}, ` +
+				`"rules": [{"http": {"paths": [{"path": "/", "pathType": "Exact", "backend": {"service": {"name": "api", "port": {"number": 8080}}}}]}}]}}`,
+			want: `{"spec": {"backend": {"serviceName": "web", "servicePort": "http"}, ` +
+				`"rules": [{"http": {"paths": [{"path": "/", "pathType": "Exact", "backend": {"serviceName": "api", "servicePort": 8080}}]}}]}}`,
+			roundTrip: true,
+		},
+	}
+	for _, test := range tests {
+		out, err := ConvertIngressJSON([]byte(test.in), test.shape)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		same, err := EqualJSON(out, []byte(test.want))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if !same {
+			t.Errorf("%s: got %s, want %s", test.name, out, test.want)
+			continue
+		}
+		if !test.roundTrip {
+			continue
+		}
+		if out, err = ConvertIngressJSON(out, ingressV1); err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if same, err = EqualJSON(out, []byte(test.in)); err != nil || !same {
+			t.Errorf("%s: round trip got %s, want %s", test.name, out, test.in)
+		}
+	}
+}
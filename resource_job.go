@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:        "job",
+		Order:       OrderWorkload,
+		Group:       "batch",
+		Plural:      "jobs",
+		GVK:         schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+		Namespaced:  true,
+		PodTemplate: "/spec/template",
+		Defaults: PatchSet{
+			removeDefault("/spec/backoffLimit", 6),
+			// completions and parallelism are defaulted together
+			{
+				{Op: OpTest, Path: "/spec/completions", Value: 1},
+				{Op: OpTest, Path: "/spec/parallelism", Value: 1},
+				{Op: OpRemove, Path: "/spec/completions"},
+				{Op: OpRemove, Path: "/spec/parallelism"},
+			},
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *batchv1.JobList
+			if items, err = client.BatchV1().Jobs(namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				// ignore job created by cronjob
+				if isOwnedBy(item.OwnerReferences, "CronJob") {
+					continue
+				}
+				sanitizeJob(&item)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *batchv1.Job
+			if obj, err = client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			sanitizeJob(obj)
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj batchv1.Job
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Namespace = namespace
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "batch/v1", "Job", namespace, name); err != nil {
+					return
+				}
+				_, err = client.BatchV1().Jobs(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *batchv1.Job
+			if current, err = client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+				// selector and its labels are generated by server
+				if obj.Spec.Selector == nil {
+					obj.Spec.Selector = current.Spec.Selector
+					for _, key := range jobGeneratedLabels {
+						if value, ok := current.Spec.Template.Labels[key]; ok {
+							if obj.Spec.Template.Labels == nil {
+								obj.Spec.Template.Labels = map[string]string{}
+							}
+							obj.Spec.Template.Labels[key] = value
+						}
+					}
+				}
+			}
+
+			if _, err = client.BatchV1().Jobs(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.BatchV1().Jobs(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.BatchV1().Jobs(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "job")
+}
+
+// labels added to pod template of a job without manual selector
+var jobGeneratedLabels = []string{"controller-uid", "job-name", "batch.kubernetes.io/controller-uid", "batch.kubernetes.io/job-name"}
+
+// sanitizeJob drops the generated selector and its labels, they are bound to uid of the live job
+func sanitizeJob(job *batchv1.Job) {
+	if job.Spec.ManualSelector != nil && *job.Spec.ManualSelector {
+		return
+	}
+	job.Spec.Selector = nil
+	for _, key := range jobGeneratedLabels {
+		delete(job.Spec.Template.Labels, key)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "limitrange",
+		Order:      OrderNamespace,
+		Group:      "",
+		Plural:     "limitranges",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "LimitRange"},
+		Aliases:    []string{"limits"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *corev1.LimitRangeList
+			if items, err = client.CoreV1().LimitRanges(namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *corev1.LimitRange
+			if obj, err = client.CoreV1().LimitRanges(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj corev1.LimitRange
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Namespace = namespace
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "LimitRange", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().LimitRanges(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *corev1.LimitRange
+			if current, err = client.CoreV1().LimitRanges(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+			}
+
+			if _, err = client.CoreV1().LimitRanges(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.CoreV1().LimitRanges(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().LimitRanges(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "limitrange")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "networkpolicy",
+		Order:      OrderConfig,
+		Group:      "networking.k8s.io",
+		Plural:     "networkpolicies",
+		GVK:        schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+		Aliases:    []string{"netpol"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *networkingv1.NetworkPolicyList
+			if items, err = client.NetworkingV1().NetworkPolicies(namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *networkingv1.NetworkPolicy
+			if obj, err = client.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj networkingv1.NetworkPolicy
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Namespace = namespace
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "networking.k8s.io/v1", "NetworkPolicy", namespace, name); err != nil {
+					return
+				}
+				_, err = client.NetworkingV1().NetworkPolicies(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *networkingv1.NetworkPolicy
+			if current, err = client.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+			}
+
+			if _, err = client.NetworkingV1().NetworkPolicies(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.NetworkingV1().NetworkPolicies(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "networkpolicy")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"log"
+)
+
+// policy/v1 PodDisruptionBudget is served since kubernetes 1.21, policy/v1beta1 is removed in 1.25
+var pdbGVK = schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}
+
+// pdbV1beta1 is the typed pdb resource for clusters not serving policy/v1
+var pdbV1beta1 = &Resource{
+	Kind:       "pdb",
+	Order:      OrderAutoscaler,
+	Group:      "policy",
+	Plural:     "poddisruptionbudgets",
+	GVK:        schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+	Namespaced: true,
+	List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+		var items *policyv1beta1.PodDisruptionBudgetList
+		if items, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).List(ctx, opts); err != nil {
+			return
+		}
+		for _, item := range items.Items {
+			var data []byte
+			if data, err = json.Marshal(item); err != nil {
+				return
+			}
+			objects = append(objects, Object{Name: item.Name, JSON: data})
+		}
+		return
+	},
+	GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+		var obj *policyv1beta1.PodDisruptionBudget
+		if obj, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return
+		}
+		data, err = json.Marshal(obj)
+		return
+	},
+	SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+		var obj policyv1beta1.PodDisruptionBudget
+		if err = json.Unmarshal(data, &obj); err != nil {
+			return
+		}
+		obj.Namespace = namespace
+		obj.Name = name
+
+		if opts.ServerSide {
+			if data, err = ApplyJSON(data, "policy/v1beta1", "PodDisruptionBudget", namespace, name); err != nil {
+				return
+			}
+			_, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+			return
+		}
+
+		var current *policyv1beta1.PodDisruptionBudget
+		if current, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				err = nil
+			} else {
+				return
+			}
+		} else {
+			if IsEnvNoUpdate() {
+				log.Println("SKIP")
+				return
+			}
+			obj.ResourceVersion = current.ResourceVersion
+		}
+
+		if _, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+			if errors.IsNotFound(err) {
+				obj.ResourceVersion = ""
+				if _, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+					return
+				}
+			} else if isImmutablePDBSpecError(err) && current != nil {
+				// spec is immutable before kubernetes 1.15, delete and recreate
+				log.Println("RECREATE")
+				if err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Delete(ctx, name, opts.DeleteOptions()); err != nil {
+					return
+				}
+				// a deletion in server dry run is not persisted, creation would conflict
+				if opts.DryRun == DryRunServer {
+					return
+				}
+				obj.ResourceVersion = ""
+				if _, err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+					// put the deleted pdb back, so that a failed push does not leave the workload unprotected
+					current.ResourceVersion = ""
+					if _, restoreErr := client.PolicyV1beta1().PodDisruptionBudgets(namespace).Create(ctx, current, opts.CreateOptions()); restoreErr != nil {
+						log.Println("RESTORE FAILED:", restoreErr.Error())
+					}
+					return
+				}
+			}
+			return
+		}
+		return
+	},
+	Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+		err = client.PolicyV1beta1().PodDisruptionBudgets(namespace).Delete(ctx, name, opts.DeleteOptions())
+		return
+	},
+}
+
+// isImmutablePDBSpecError checks whether an update is rejected only because pdb spec is immutable, as before kubernetes 1.15
+func isImmutablePDBSpecError(err error) bool {
+	status, ok := err.(errors.APIStatus)
+	if !ok || !errors.IsInvalid(err) || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return false
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Field != "spec" || cause.Type != metav1.CauseType(field.ErrorTypeForbidden) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvePDBResource prefers policy/v1 via dynamic client if served, otherwise policy/v1beta1
+func resolvePDBResource(client *Client) *Resource {
+	if mapping, err := client.Mapper.RESTMapping(pdbGVK.GroupKind(), pdbGVK.Version); err == nil {
+		resource := newDynamicResource(pdbGVK, mapping.Resource, true)
+		resource.Kind = pdbV1beta1.Kind
+		return resource
+	}
+	return pdbV1beta1
+}
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       pdbV1beta1.Kind,
+		Order:      pdbV1beta1.Order,
+		Group:      pdbV1beta1.Group,
+		Plural:     pdbV1beta1.Plural,
+		Aliases:    []string{"poddisruptionbudget"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) ([]Object, error) {
+			return resolvePDBResource(client).ListTyped(ctx, client, namespace, opts)
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) ([]byte, error) {
+			return resolvePDBResource(client).GetTypedJSON(ctx, client, namespace, name)
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) error {
+			return resolvePDBResource(client).SetJSON(ctx, client, namespace, name, data, opts)
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) error {
+			return resolvePDBResource(client).Delete(ctx, client, namespace, name, opts)
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "pdb")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+	"strings"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "priorityclass",
+		Order:      OrderConfig,
+		Group:      "scheduling.k8s.io",
+		Plural:     "priorityclasses",
+		GVK:        schema.GroupVersionKind{Group: "scheduling.k8s.io", Version: "v1", Kind: "PriorityClass"},
+		Aliases:    []string{"pc"},
+		Namespaced: false,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *schedulingv1.PriorityClassList
+			if items, err = client.SchedulingV1().PriorityClasses().List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				// ignore objects managed by kubernetes itself
+				if strings.HasPrefix(item.Name, "system-") {
+					continue
+				}
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *schedulingv1.PriorityClass
+			if obj, err = client.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj schedulingv1.PriorityClass
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "scheduling.k8s.io/v1", "PriorityClass", "", name); err != nil {
+					return
+				}
+				_, err = client.SchedulingV1().PriorityClasses().Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *schedulingv1.PriorityClass
+			if current, err = client.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+			}
+
+			if _, err = client.SchedulingV1().PriorityClasses().Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.SchedulingV1().PriorityClasses().Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.SchedulingV1().PriorityClasses().Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "priorityclass")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "pv",
+		Order:      OrderStorage,
+		Group:      "",
+		Plural:     "persistentvolumes",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolume"},
+		Aliases:    []string{"persistentvolume"},
+		Namespaced: false,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *corev1.PersistentVolumeList
+			if items, err = client.CoreV1().PersistentVolumes().List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				if data, err = pvAssignedFields.Apply(data); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *corev1.PersistentVolume
+			if obj, err = client.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			if data, err = json.Marshal(obj); err != nil {
+				return
+			}
+			data, err = pvAssignedFields.Apply(data)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			if data, err = pvAssignedFields.Apply(data); err != nil {
+				return
+			}
+			var obj corev1.PersistentVolume
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "PersistentVolume", "", name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().PersistentVolumes().Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *corev1.PersistentVolume
+			if current, err = client.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+				// keep the binding to the claim of this cluster
+				if obj.Spec.ClaimRef != nil && current.Spec.ClaimRef != nil &&
+					obj.Spec.ClaimRef.Namespace == current.Spec.ClaimRef.Namespace && obj.Spec.ClaimRef.Name == current.Spec.ClaimRef.Name {
+					obj.Spec.ClaimRef.UID = current.Spec.ClaimRef.UID
+					obj.Spec.ClaimRef.ResourceVersion = current.Spec.ClaimRef.ResourceVersion
+				}
+			}
+
+			if _, err = client.CoreV1().PersistentVolumes().Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.CoreV1().PersistentVolumes().Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().PersistentVolumes().Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "pv")
+}
+
+// pvAssignedFields drops the claim reference bound to uid of the claim in source cluster
+var pvAssignedFields = PatchSet{
+	{{Op: OpRemove, Path: "/spec/claimRef/uid"}},
+	{{Op: OpRemove, Path: "/spec/claimRef/resourceVersion"}},
+}
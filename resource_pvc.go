@@ -6,32 +6,56 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
+	"strings"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "pvc",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:       "pvc",
+		Order:      OrderStorage,
+		Group:      "",
+		Plural:     "persistentvolumeclaims",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"},
+		Aliases:    []string{"persistentvolumeclaim"},
+		Namespaced: true,
+		Defaults: PatchSet{
+			removeDefault("/spec/volumeMode", "Filesystem"),
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *corev1.PersistentVolumeClaimList
-			if items, err = client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				if data, err = pvcAssignedFields.Apply(data); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *corev1.PersistentVolumeClaim
 			if obj, err = client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
 			}
-			data, err = json.Marshal(obj)
+			if data, err = json.Marshal(obj); err != nil {
+				return
+			}
+			data, err = pvcAssignedFields.Apply(data)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			if data, err = pvcAssignedFields.Apply(data); err != nil {
+				return
+			}
 			var obj corev1.PersistentVolumeClaim
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -39,6 +63,14 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "PersistentVolumeClaim", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
 			var current *corev1.PersistentVolumeClaim
 			if current, err = client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -52,12 +84,22 @@ func init() {
 					return
 				}
 				obj.ResourceVersion = current.ResourceVersion
+				// keep the bound volume
+				obj.Spec.VolumeName = current.Spec.VolumeName
+				for key, value := range current.Annotations {
+					if strings.HasPrefix(key, pvAnnotationPrefix) {
+						if obj.Annotations == nil {
+							obj.Annotations = map[string]string{}
+						}
+						obj.Annotations[key] = value
+					}
+				}
 			}
 
-			if _, err = client.CoreV1().PersistentVolumeClaims(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.CoreV1().PersistentVolumeClaims(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
-					if _, err = client.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -65,6 +107,18 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "pvc")
 }
+
+const pvAnnotationPrefix = "pv.kubernetes.io/"
+
+// pvcAssignedFields drops the bound volume, which does not exist in other clusters
+var pvcAssignedFields = PatchSet{
+	{{Op: OpRemove, Path: "/spec/volumeName"}},
+	{{Op: OpRemove, Path: "/metadata/annotations/pv.kubernetes.io~1*"}},
+}
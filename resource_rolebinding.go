@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "rolebinding",
+		Order:      OrderConfig,
+		Group:      "rbac.authorization.k8s.io",
+		Plural:     "rolebindings",
+		GVK:        schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *rbacv1.RoleBindingList
+			if items, err = client.RbacV1().RoleBindings(namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				localizeSubjects(&item)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *rbacv1.RoleBinding
+			if obj, err = client.RbacV1().RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			localizeSubjects(obj)
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			if data, err = bindSubjects(data, namespace); err != nil {
+				return
+			}
+			var obj rbacv1.RoleBinding
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Namespace = namespace
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "rbac.authorization.k8s.io/v1", "RoleBinding", namespace, name); err != nil {
+					return
+				}
+				_, err = client.RbacV1().RoleBindings(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *rbacv1.RoleBinding
+			if current, err = client.RbacV1().RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+			}
+
+			if _, err = client.RbacV1().RoleBindings(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.RbacV1().RoleBindings(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.RbacV1().RoleBindings(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "rolebinding")
+}
+
+// localizeSubjects clears namespace of service account subjects in the namespace of the binding,
+// so that a pulled binding follows the namespace it is pushed to
+func localizeSubjects(rb *rbacv1.RoleBinding) {
+	for i, subject := range rb.Subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == rb.Namespace {
+			rb.Subjects[i].Namespace = ""
+		}
+	}
+}
+
+// bindSubjects sets namespace of service account subjects without namespace to the target namespace
+func bindSubjects(data []byte, namespace string) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	subjects, _ := m["subjects"].([]interface{})
+	for _, subject := range subjects {
+		subject, ok := subject.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if subject["kind"] == rbacv1.ServiceAccountKind && subject["namespace"] == nil {
+			subject["namespace"] = namespace
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
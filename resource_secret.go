@@ -6,17 +6,23 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
-	"strconv"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "secret",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:       "secret",
+		Order:      OrderConfig,
+		Group:      "",
+		Plural:     "secrets",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		Namespaced: true,
+		Encrypted:  true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *corev1.SecretList
-			if items, err = client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.CoreV1().Secrets(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
@@ -24,15 +30,15 @@ func init() {
 				if item.Type == corev1.SecretTypeServiceAccountToken {
 					continue
 				}
-				// ignore replicated secret
-				if replicated, _ := strconv.ParseBool(item.Annotations["autoops.auto-replicate-secret/replicated"]); replicated {
-					continue
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
 				}
-				names = append(names, item.Name)
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *corev1.Secret
 			if obj, err = client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
@@ -40,7 +46,7 @@ func init() {
 			data, err = json.Marshal(obj)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			var obj corev1.Secret
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -48,6 +54,14 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "Secret", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().Secrets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
 			var current *corev1.Secret
 			if current, err = client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -63,10 +77,10 @@ func init() {
 				obj.ResourceVersion = current.ResourceVersion
 			}
 
-			if _, err = client.CoreV1().Secrets(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.CoreV1().Secrets(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
-					if _, err = client.CoreV1().Secrets(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.CoreV1().Secrets(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -74,6 +88,10 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().Secrets(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "secret")
 }
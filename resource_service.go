@@ -6,7 +6,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
@@ -17,18 +18,39 @@ const (
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "service",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:       "service",
+		Order:      OrderService,
+		Group:      "",
+		Plural:     "services",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Aliases:    []string{"svc"},
+		Namespaced: true,
+		Defaults: PatchSet{
+			removeDefault("/spec/type", "ClusterIP"),
+			removeDefault("/spec/sessionAffinity", "None"),
+			removeDefault("/spec/ports/*/protocol", "TCP"),
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *corev1.ServiceList
-			if items, err = client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.CoreV1().Services(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				if namespace == keyDefault && item.Name == keyKubernetes {
+					continue
+				}
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				if data, err = sanitizeServiceJSON(data); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			if namespace == keyDefault && name == keyKubernetes {
 				return
 			}
@@ -36,14 +58,20 @@ func init() {
 			if obj, err = client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
 			}
-			data, err = json.Marshal(obj)
+			if data, err = json.Marshal(obj); err != nil {
+				return
+			}
+			data, err = sanitizeServiceJSON(data)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			if namespace == keyDefault && name == keyKubernetes {
 				return
 			}
 
+			if data, err = sanitizeServiceJSON(data); err != nil {
+				return
+			}
 			var obj corev1.Service
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -51,6 +79,14 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "Service", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().Services(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
 			var current *corev1.Service
 			if current, err = client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -64,12 +100,20 @@ func init() {
 					return
 				}
 				obj.ResourceVersion = current.ResourceVersion
+				// keep values assigned by cluster
+				obj.Spec.ClusterIP = current.Spec.ClusterIP
+				if obj.Spec.Type == corev1.ServiceTypeLoadBalancer && obj.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyTypeLocal {
+					obj.Spec.HealthCheckNodePort = current.Spec.HealthCheckNodePort
+				}
+				if obj.Spec.Type == corev1.ServiceTypeNodePort || obj.Spec.Type == corev1.ServiceTypeLoadBalancer {
+					keepServiceNodePorts(obj.Spec.Ports, current.Spec.Ports)
+				}
 			}
 
-			if _, err = client.CoreV1().Services(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.CoreV1().Services(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
-					if _, err = client.CoreV1().Services(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.CoreV1().Services(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -77,6 +121,59 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			if namespace == keyDefault && name == keyKubernetes {
+				return
+			}
+			err = client.CoreV1().Services(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "service")
 }
+
+// keepServiceNodePorts copies 'nodePort' of live ports to ports without one, matched by name, or by port number if unnamed
+func keepServiceNodePorts(ports, live []corev1.ServicePort) {
+	protocol := func(port corev1.ServicePort) corev1.Protocol {
+		if port.Protocol == "" {
+			return corev1.ProtocolTCP
+		}
+		return port.Protocol
+	}
+	for i := range ports {
+		if ports[i].NodePort != 0 {
+			continue
+		}
+		for _, port := range live {
+			if (ports[i].Name != "" && ports[i].Name == port.Name) ||
+				(ports[i].Name == "" && ports[i].Port == port.Port && protocol(ports[i]) == protocol(port)) {
+				ports[i].NodePort = port.NodePort
+				break
+			}
+		}
+	}
+}
+
+// sanitizeServiceJSON drops values assigned by cluster, 'clusterIP' and 'clusterIPs' of headless services are kept
+func sanitizeServiceJSON(data []byte) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	if spec, ok := m["spec"].(map[string]interface{}); ok {
+		if spec["clusterIP"] != corev1.ClusterIPNone {
+			delete(spec, "clusterIP")
+			delete(spec, "clusterIPs")
+		}
+		delete(spec, "healthCheckNodePort")
+		if ports, ok := spec["ports"].([]interface{}); ok {
+			for _, port := range ports {
+				if port, ok := port.(map[string]interface{}); ok {
+					delete(port, "nodePort")
+				}
+			}
+		}
+	}
+	out, err = json.Marshal(m)
+	return
+}
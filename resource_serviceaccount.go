@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"log"
+	"strings"
+)
+
+func init() {
+	knownResources = append(knownResources, &Resource{
+		Kind:       "serviceaccount",
+		Order:      OrderConfig,
+		Group:      "",
+		Plural:     "serviceaccounts",
+		GVK:        schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"},
+		Aliases:    []string{"sa"},
+		Namespaced: true,
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
+			var items *corev1.ServiceAccountList
+			if items, err = client.CoreV1().ServiceAccounts(namespace).List(ctx, opts); err != nil {
+				return
+			}
+			for _, item := range items.Items {
+				sanitizeServiceAccount(&item)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
+			}
+			return
+		},
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
+			var obj *corev1.ServiceAccount
+			if obj, err = client.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			sanitizeServiceAccount(obj)
+			data, err = json.Marshal(obj)
+			return
+		},
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
+			var obj corev1.ServiceAccount
+			if err = json.Unmarshal(data, &obj); err != nil {
+				return
+			}
+			obj.Namespace = namespace
+			obj.Name = name
+
+			if opts.ServerSide {
+				if data, err = ApplyJSON(data, "v1", "ServiceAccount", namespace, name); err != nil {
+					return
+				}
+				_, err = client.CoreV1().ServiceAccounts(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions())
+				return
+			}
+
+			var current *corev1.ServiceAccount
+			if current, err = client.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					err = nil
+				} else {
+					return
+				}
+			} else {
+				if IsEnvNoUpdate() {
+					log.Println("SKIP")
+					return
+				}
+				obj.ResourceVersion = current.ResourceVersion
+				// keep token references maintained by token controller
+				for _, ref := range current.Secrets {
+					if isServiceAccountToken(current, ref.Name) {
+						obj.Secrets = append(obj.Secrets, ref)
+					}
+				}
+			}
+
+			if _, err = client.CoreV1().ServiceAccounts(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
+				if errors.IsNotFound(err) {
+					obj.ResourceVersion = ""
+					if _, err = client.CoreV1().ServiceAccounts(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
+						return
+					}
+				}
+				return
+			}
+			return
+		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+	})
+	knownResourceNames = append(knownResourceNames, "serviceaccount")
+}
+
+// isServiceAccountToken checks whether a secret name is an auto-generated token of the service account
+func isServiceAccountToken(sa *corev1.ServiceAccount, name string) bool {
+	return strings.HasPrefix(name, sa.Name+"-token-")
+}
+
+// sanitizeServiceAccount drops references to auto-generated token secrets, which are never pulled
+func sanitizeServiceAccount(sa *corev1.ServiceAccount) {
+	var secrets []corev1.ObjectReference
+	for _, ref := range sa.Secrets {
+		if !isServiceAccountToken(sa, ref.Name) {
+			secrets = append(secrets, ref)
+		}
+	}
+	sa.Secrets = secrets
+	var pullSecrets []corev1.LocalObjectReference
+	for _, ref := range sa.ImagePullSecrets {
+		if !isServiceAccountToken(sa, ref.Name) {
+			pullSecrets = append(pullSecrets, ref)
+		}
+	}
+	sa.ImagePullSecrets = pullSecrets
+}
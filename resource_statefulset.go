@@ -3,27 +3,49 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	appv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"log"
 )
 
 func init() {
 	knownResources = append(knownResources, &Resource{
-		Kind: "statefulset",
-		List: func(ctx context.Context, client *kubernetes.Clientset, namespace string) (names []string, err error) {
+		Kind:        "statefulset",
+		Order:       OrderWorkload,
+		Group:       "apps",
+		Plural:      "statefulsets",
+		GVK:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		Aliases:     []string{"sts"},
+		Namespaced:  true,
+		PodTemplate: "/spec/template",
+		Defaults: PatchSet{
+			removeDefault("/spec/revisionHistoryLimit", 10),
+			removeDefault("/spec/podManagementPolicy", "OrderedReady"),
+			removeDefault("/spec/updateStrategy", map[string]interface{}{
+				"type":          "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{"partition": 0},
+			}),
+			removeDefault("/spec/volumeClaimTemplates/*/spec/volumeMode", "Filesystem"),
+		},
+		List: func(ctx context.Context, client *Client, namespace string, opts metav1.ListOptions) (objects []Object, err error) {
 			var items *appv1.StatefulSetList
-			if items, err = client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			if items, err = client.AppsV1().StatefulSets(namespace).List(ctx, opts); err != nil {
 				return
 			}
 			for _, item := range items.Items {
-				names = append(names, item.Name)
+				var data []byte
+				if data, err = json.Marshal(item); err != nil {
+					return
+				}
+				objects = append(objects, Object{Name: item.Name, JSON: data})
 			}
 			return
 		},
-		GetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string) (data []byte, err error) {
+		GetJSON: func(ctx context.Context, client *Client, namespace, name string) (data []byte, err error) {
 			var obj *appv1.StatefulSet
 			if obj, err = client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				return
@@ -31,7 +53,7 @@ func init() {
 			data, err = json.Marshal(obj)
 			return
 		},
-		SetJSON: func(ctx context.Context, client *kubernetes.Clientset, namespace, name string, data []byte) (err error) {
+		SetJSON: func(ctx context.Context, client *Client, namespace, name string, data []byte, opts PushOptions) (err error) {
 			var obj appv1.StatefulSet
 			if err = json.Unmarshal(data, &obj); err != nil {
 				return
@@ -39,6 +61,37 @@ func init() {
 			obj.Namespace = namespace
 			obj.Name = name
 
+			if opts.ServerSide {
+				var zero bool
+				if IsEnvZeroReplicas() {
+					if _, err = client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{}); errors.IsNotFound(err) {
+						zero, err = true, nil
+					} else if err != nil {
+						return
+					}
+				}
+				if data, err = ApplyJSON(data, "apps/v1", "StatefulSet", namespace, name); err != nil {
+					return
+				}
+				if zero {
+					if data, err = ZeroReplicasJSON(data); err != nil {
+						return
+					}
+				}
+				if _, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.PatchOptions()); err != nil || !zero || opts.DryRun == DryRunServer {
+					return
+				}
+				// replicas is also applied by another manager, which keeps it at 0 once later pushes leave it out
+				if data, err = ZeroReplicasJSON(nil); err != nil {
+					return
+				}
+				if data, err = ApplyJSON(data, "apps/v1", "StatefulSet", namespace, name); err != nil {
+					return
+				}
+				_, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.ApplyPatchType, data, opts.ZeroReplicasPatchOptions())
+				return
+			}
+
 			var current *appv1.StatefulSet
 			if current, err = client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 				if errors.IsNotFound(err) {
@@ -55,13 +108,13 @@ func init() {
 				obj.Spec.Replicas = current.Spec.Replicas
 			}
 
-			if _, err = client.AppsV1().StatefulSets(namespace).Update(ctx, &obj, metav1.UpdateOptions{}); err != nil {
+			if _, err = client.AppsV1().StatefulSets(namespace).Update(ctx, &obj, opts.UpdateOptions()); err != nil {
 				if errors.IsNotFound(err) {
 					obj.ResourceVersion = ""
 					if IsEnvZeroReplicas() {
 						obj.Spec.Replicas = &int32Zero
 					}
-					if _, err = client.AppsV1().StatefulSets(namespace).Create(ctx, &obj, metav1.CreateOptions{}); err != nil {
+					if _, err = client.AppsV1().StatefulSets(namespace).Create(ctx, &obj, opts.CreateOptions()); err != nil {
 						return
 					}
 				}
@@ -69,6 +122,42 @@ func init() {
 			}
 			return
 		},
+		Delete: func(ctx context.Context, client *Client, namespace, name string, opts PushOptions) (err error) {
+			err = client.AppsV1().StatefulSets(namespace).Delete(ctx, name, opts.DeleteOptions())
+			return
+		},
+		Rollout: func(ctx context.Context, client *Client, namespace, name string) (status RolloutStatus, err error) {
+			var obj *appv1.StatefulSet
+			if obj, err = client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return
+			}
+			status.Selector = revisionSelector(obj.Spec.Selector, appv1.StatefulSetRevisionLabel, obj.Status.UpdateRevision)
+			replicas := int32(1)
+			if obj.Spec.Replicas != nil {
+				replicas = *obj.Spec.Replicas
+			}
+			switch {
+			case obj.Status.ObservedGeneration < obj.Generation:
+				status.Message = "waiting for statefulset spec update to be observed"
+			case obj.Spec.UpdateStrategy.Type == appv1.OnDeleteStatefulSetStrategyType:
+				// pods are only updated when deleted manually
+				status.Done = true
+			case obj.Status.ReadyReplicas < replicas:
+				status.Message = fmt.Sprintf("%d of %d replicas ready", obj.Status.ReadyReplicas, replicas)
+			case obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Partition != nil:
+				partition := *obj.Spec.UpdateStrategy.RollingUpdate.Partition
+				if obj.Status.UpdatedReplicas < replicas-partition {
+					status.Message = fmt.Sprintf("%d of %d partitioned replicas updated", obj.Status.UpdatedReplicas, replicas-partition)
+				} else {
+					status.Done = true
+				}
+			case obj.Status.UpdateRevision != obj.Status.CurrentRevision:
+				status.Message = fmt.Sprintf("%d of %d replicas updated", obj.Status.UpdatedReplicas, replicas)
+			default:
+				status.Done = true
+			}
+			return
+		},
 	})
 	knownResourceNames = append(knownResourceNames, "statefulset")
 }
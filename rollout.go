@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	rolloutInterval = 2 * time.Second
+	// rolloutStuckPolls is the number of consecutive polls pods must stay in back off to fail a rollout
+	rolloutStuckPolls = 3
+)
+
+var (
+	// stuckReasons are waiting reasons of containers that failed repeatedly, errors right after a push are often transient
+	stuckReasons = map[string]bool{
+		"CrashLoopBackOff": true,
+		"ImagePullBackOff": true,
+	}
+)
+
+type RolloutStatus struct {
+	Done    bool
+	Message string
+	// Selector selects pods of the current revision, nil if the revision is not known yet
+	Selector *metav1.LabelSelector
+}
+
+// revisionSelector narrows a workload selector to pods labeled with a revision, nil if revision is empty
+func revisionSelector(selector *metav1.LabelSelector, key, revision string) *metav1.LabelSelector {
+	if selector == nil || revision == "" {
+		return nil
+	}
+	out := selector.DeepCopy()
+	if out.MatchLabels == nil {
+		out.MatchLabels = map[string]string{}
+	}
+	out.MatchLabels[key] = revision
+	return out
+}
+
+type rolloutTarget struct {
+	client    *Client
+	resource  *Resource
+	cluster   string
+	namespace string
+	name      string
+}
+
+// less orders targets in visiting order, then by kind order and name, objects of a kind are pushed concurrently
+func (t rolloutTarget) less(o rolloutTarget) bool {
+	if t.cluster != o.cluster || t.namespace != o.namespace {
+		return lessVisited(t.cluster, t.namespace, o.cluster, o.namespace)
+	}
+	if t.resource.Order != o.resource.Order {
+		return t.resource.Order < o.resource.Order
+	}
+	return t.name < o.name
+}
+
+func (t rolloutTarget) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", t.cluster, t.namespace, t.resource.Kind, t.name)
+}
+
+// deploymentRevisionAnnotation is the revision of a deployment and of its replica sets
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// deploymentPodSelector selects pods of the replica set of the current revision of a deployment
+func deploymentPodSelector(ctx context.Context, client *Client, obj *appv1.Deployment) (selector *metav1.LabelSelector, err error) {
+	var items *appv1.ReplicaSetList
+	if items, err = client.AppsV1().ReplicaSets(obj.Namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(obj.Spec.Selector)}); err != nil {
+		return
+	}
+	revision := obj.Annotations[deploymentRevisionAnnotation]
+	for i := range items.Items {
+		rs := &items.Items[i]
+		if metav1.IsControlledBy(rs, obj) && revision != "" && rs.Annotations[deploymentRevisionAnnotation] == revision {
+			selector = revisionSelector(obj.Spec.Selector, appv1.DefaultDeploymentUniqueLabelKey, rs.Labels[appv1.DefaultDeploymentUniqueLabelKey])
+			return
+		}
+	}
+	return
+}
+
+// daemonSetPodSelector selects pods of the newest controller revision of a daemonset
+func daemonSetPodSelector(ctx context.Context, client *Client, obj *appv1.DaemonSet) (selector *metav1.LabelSelector, err error) {
+	var items *appv1.ControllerRevisionList
+	if items, err = client.AppsV1().ControllerRevisions(obj.Namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(obj.Spec.Selector)}); err != nil {
+		return
+	}
+	var newest *appv1.ControllerRevision
+	for i := range items.Items {
+		revision := &items.Items[i]
+		if metav1.IsControlledBy(revision, obj) && (newest == nil || revision.Revision > newest.Revision) {
+			newest = revision
+		}
+	}
+	if newest != nil {
+		selector = revisionSelector(obj.Spec.Selector, appv1.DefaultDaemonSetUniqueLabelKey, newest.Labels[appv1.DefaultDaemonSetUniqueLabelKey])
+	}
+	return
+}
+
+// findStuckPods returns messages of containers stuck in back off
+func findStuckPods(ctx context.Context, client *Client, namespace string, selector *metav1.LabelSelector) (messages []string, err error) {
+	if selector == nil {
+		return
+	}
+	var items *corev1.PodList
+	if items, err = client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(selector)}); err != nil {
+		return
+	}
+	for _, pod := range items.Items {
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.State.Waiting == nil || !stuckReasons[status.State.Waiting.Reason] {
+					continue
+				}
+				messages = append(messages, fmt.Sprintf("%s/%s: %s: %s", pod.Name, status.Name, status.State.Waiting.Reason, status.State.Waiting.Message))
+			}
+		}
+	}
+	return
+}
+
+func waitRollout(ctx context.Context, target rolloutTarget, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("WAIT: %s", target)
+	var last RolloutStatus
+	var stuck []string
+	var stuckPolls int
+	if err = wait.PollImmediateUntil(rolloutInterval, func() (done bool, err error) {
+		if last, err = target.resource.Rollout(ctx, target.client, target.namespace, target.name); err != nil {
+			return
+		}
+		if last.Done {
+			done = true
+			return
+		}
+		if stuck, err = findStuckPods(ctx, target.client, target.namespace, last.Selector); err != nil {
+			return
+		}
+		if len(stuck) == 0 {
+			stuckPolls = 0
+			return
+		}
+		stuckPolls++
+		done = stuckPolls >= rolloutStuckPolls
+		return
+	}, ctx.Done()); err != nil {
+		if err == wait.ErrWaitTimeout {
+			err = fmt.Errorf("timed out waiting for rollout of %s: %s", target, last.Message)
+		}
+		return
+	}
+	if len(stuck) > 0 {
+		err = fmt.Errorf("rollout of %s is stuck:\n\t%s", target, strings.Join(stuck, "\n\t"))
+		return
+	}
+	log.Printf("ROLLOUT: %s: complete", target)
+	return
+}
@@ -3,6 +3,11 @@ package main
 import (
 	"encoding/json"
 	jsonpatch "github.com/evanphx/json-patch"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -14,29 +19,57 @@ const (
 	OpTest    = "test"
 )
 
+// Patch is a JSON patch operation, segments of Path and From with any of '*?[{' are wildcards, see ParsePattern,
+// they match keys of objects, '*' also matches indexes of arrays
 type Patch struct {
-	Op    string      `json:"op,omitempty"`
-	Path  string      `json:"path,omitempty"`
-	From  string      `json:"from,omitempty"`
-	Value interface{} `json:"value,omitempty"`
+	Op    string      `json:"op,omitempty" yaml:"op,omitempty"`
+	Path  string      `json:"path,omitempty" yaml:"path,omitempty"`
+	From  string      `json:"from,omitempty" yaml:"from,omitempty"`
+	Value interface{} `json:"value" yaml:"value,omitempty"`
 }
 
+// Patches are applied together, a failed operation discards the whole group;
+// wildcards are expanded by the first patch with wildcards, the other patches take the same matched segments in order
 type Patches []Patch
 
+// UnmarshalYAML accepts a single patch in place of a group
+func (ps *Patches) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		var p Patch
+		if err := node.Decode(&p); err != nil {
+			return err
+		}
+		*ps = Patches{p}
+		return nil
+	}
+	var items []Patch
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	*ps = items
+	return nil
+}
+
 type PatchSet []Patches
 
 func (ps PatchSet) Apply(data []byte) (out []byte, err error) {
 	for _, item := range ps {
-		var buf []byte
-		if buf, err = json.Marshal(item); err != nil {
+		var groups []Patches
+		if groups, err = item.expand(data); err != nil {
 			return
 		}
-		var patch jsonpatch.Patch
-		if patch, err = jsonpatch.DecodePatch(buf); err != nil {
-			return
-		}
-		if buf, err = patch.Apply(data); err == nil {
-			data = buf
+		for _, group := range groups {
+			var buf []byte
+			if buf, err = json.Marshal(group); err != nil {
+				return
+			}
+			var patch jsonpatch.Patch
+			if patch, err = jsonpatch.DecodePatch(buf); err != nil {
+				return
+			}
+			if buf, err = patch.Apply(data); err == nil {
+				data = buf
+			}
 		}
 	}
 	out = data
@@ -44,6 +77,142 @@ func (ps PatchSet) Apply(data []byte) (out []byte, err error) {
 	return
 }
 
+func unescapePointerSegment(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
+
+func escapePointerSegment(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func isWildcardSegment(s string) bool {
+	return strings.ContainsAny(unescapePointerSegment(s), patternGlobChars)
+}
+
+func isWildcardPointer(pointer string) bool {
+	for _, seg := range strings.Split(pointer, "/") {
+		if isWildcardSegment(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPointer resolves wildcard segments of a pointer against a document, returns escaped segments matched by wildcards
+// of each concrete pointer, in document order
+func expandPointer(doc interface{}, pointer string) (matches [][]string, err error) {
+	segs := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	var walk func(node interface{}, segs []string, matched []string) error
+	walk = func(node interface{}, segs []string, matched []string) error {
+		if len(segs) == 0 {
+			matches = append(matches, matched)
+			return nil
+		}
+		seg := segs[0]
+		if !isWildcardSegment(seg) {
+			var child interface{}
+			switch node := node.(type) {
+			case map[string]interface{}:
+				child = node[unescapePointerSegment(seg)]
+			case []interface{}:
+				if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(node) {
+					child = node[i]
+				}
+			}
+			// the last segment may not exist yet, for example to add
+			if child == nil && len(segs) > 1 {
+				return nil
+			}
+			return walk(child, segs[1:], matched)
+		}
+		expr, err := globToRegexp(unescapePointerSegment(seg))
+		if err != nil {
+			return err
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		switch node := node.(type) {
+		case map[string]interface{}:
+			var keys []string
+			for key := range node {
+				if re.MatchString(key) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if err := walk(node[key], segs[1:], append(append([]string{}, matched...), escapePointerSegment(key))); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			if seg != "*" {
+				return nil
+			}
+			for i := range node {
+				if err := walk(node[i], segs[1:], append(append([]string{}, matched...), strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = walk(doc, segs, nil)
+	return
+}
+
+// substitutePointer replaces wildcard segments of a pointer with matched segments in order
+func substitutePointer(pointer string, segments []string) string {
+	if pointer == "" {
+		return pointer
+	}
+	segs := strings.Split(pointer, "/")
+	for i, seg := range segs {
+		if len(segments) == 0 {
+			break
+		}
+		if isWildcardSegment(seg) {
+			segs[i], segments = segments[0], segments[1:]
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// expand expands wildcards into concrete groups, in reverse document order so that removals keep array indexes valid
+func (ps Patches) expand(data []byte) (groups []Patches, err error) {
+	var pointer string
+	for _, p := range ps {
+		if isWildcardPointer(p.Path) {
+			pointer = p.Path
+			break
+		}
+	}
+	if pointer == "" {
+		groups = []Patches{ps}
+		return
+	}
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return
+	}
+	var matches [][]string
+	if matches, err = expandPointer(doc, pointer); err != nil {
+		return
+	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		group := make(Patches, 0, len(ps))
+		for _, p := range ps {
+			p.Path = substitutePointer(p.Path, matches[i])
+			p.From = substitutePointer(p.From, matches[i])
+			group = append(group, p)
+		}
+		groups = append(groups, group)
+	}
+	return
+}
+
 var (
 	defaultSanitizers = PatchSet{
 		{{Op: OpRemove, Path: "/status"}},
@@ -55,12 +224,17 @@ var (
 		{{Op: OpRemove, Path: "/metadata/generation"}},
 		{{Op: OpRemove, Path: "/metadata/selfLink"}},
 		{{Op: OpRemove, Path: "/metadata/uid"}},
+		{{Op: OpRemove, Path: "/metadata/resourceVersion"}},
 		{{Op: OpRemove, Path: "/metadata/managedFields"}},
 		{{Op: OpRemove, Path: "/metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration"}},
 		{{Op: OpRemove, Path: "/metadata/annotations/deployment.kubernetes.io~1revision"}},
 		{{Op: OpRemove, Path: "/metadata/annotations/field.cattle.io~1ingressState"}},
 		{{Op: OpRemove, Path: "/metadata/annotations/field.cattle.io~1publicEndpoints"}},
+		{{Op: OpRemove, Path: "/metadata/annotations/autoscaling.alpha.kubernetes.io~1conditions"}},
+		{{Op: OpRemove, Path: "/metadata/annotations/autoscaling.alpha.kubernetes.io~1current-metrics"}},
 		{{Op: OpRemove, Path: "/spec/template/metadata/creationTimestamp"}},
+		{{Op: OpRemove, Path: "/spec/jobTemplate/metadata/creationTimestamp"}},
+		{{Op: OpRemove, Path: "/spec/jobTemplate/spec/template/metadata/creationTimestamp"}},
 		{{Op: OpRemove, Path: "/spec/replicas"}},
 	}
 )
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExpandPointer(t *testing.T) {
+	doc := `{"a": [{"b": [1, 2]}, {"b": [3]}], "m": {"x.io/one": 1, "x.io/two": 2, "y.io/three": 3}}`
+	tests := []struct {
+		pointer string
+		want    [][]string
+	}{
+		{"/a/*/b/*", [][]string{{"0", "0"}, {"0", "1"}, {"1", "0"}}},
+		{"/a/1/b/*", [][]string{{"0"}}},
+		{"/m/x.io~1*", [][]string{{"x.io~1one"}, {"x.io~1two"}}},
+		{"/m/{x,y}.io~1t*", [][]string{{"x.io~1two"}, {"y.io~1three"}}},
+		// globs other than '*' do not match array indexes
+		{"/a/?", nil},
+		{"/missing/*", nil},
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		got, err := expandPointer(v, test.pointer)
+		if err != nil {
+			t.Errorf("%s: %s", test.pointer, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.pointer, got, test.want)
+		}
+	}
+}
+
+func TestSubstitutePointer(t *testing.T) {
+	tests := []struct {
+		pointer  string
+		segments []string
+		want     string
+	}{
+		{"/a/*/b/*", []string{"0", "1"}, "/a/0/b/1"},
+		{"/a/*/c", []string{"2", "3"}, "/a/2/c"},
+		{"/m/x.io~1*", []string{"x.io~1one"}, "/m/x.io~1one"},
+		{"/plain", []string{"0"}, "/plain"},
+		{"", []string{"0"}, ""},
+	}
+	for _, test := range tests {
+		if got := substitutePointer(test.pointer, test.segments); got != test.want {
+			t.Errorf("%s %v: got %s, want %s", test.pointer, test.segments, got, test.want)
+		}
+	}
+}
+
+func TestPatchSetApplyWildcards(t *testing.T) {
+	tests := []struct {
+		name string
+		ps   PatchSet
+		in   string
+		want string
+	}{
+		{
+			name: "nested removal on arrays",
+			ps:   PatchSet{removeDefault("/containers/*/ports/*/protocol", "TCP")},
+			in:   `{"containers": [{"ports": [{"port": 1, "protocol": "TCP"}, {"port": 2, "protocol": "UDP"}]}, {"ports": [{"port": 3, "protocol": "TCP"}]}]}`,
+			want: `{"containers": [{"ports": [{"port": 1}, {"port": 2, "protocol": "UDP"}]}, {"ports": [{"port": 3}]}]}`,
+		},
+		{
+			name: "removal of array items in reverse order",
+			ps:   PatchSet{{{Op: OpTest, Path: "/env/*/name", Value: "DROP"}, {Op: OpRemove, Path: "/env/*"}}},
+			in:   `{"env": [{"name": "DROP"}, {"name": "KEEP"}, {"name": "DROP"}, {"name": "DROP"}]}`,
+			want: `{"env": [{"name": "KEEP"}]}`,
+		},
+		{
+			name: "nested removal of array items",
+			ps:   PatchSet{{{Op: OpTest, Path: "/a/*/b/*", Value: 0.0}, {Op: OpRemove, Path: "/a/*/b/*"}}},
+			in:   `{"a": [{"b": [0, 1, 0]}, {"b": [0]}]}`,
+			want: `{"a": [{"b": [1]}, {"b": []}]}`,
+		},
+		{
+			name: "glob on escaped keys",
+			ps:   PatchSet{{{Op: OpRemove, Path: "/metadata/annotations/sidecar.istio.io~1*"}}},
+			in:   `{"metadata": {"annotations": {"sidecar.istio.io/status": "x", "sidecar.istio.io/inject": "true", "other": "y"}}}`,
+			want: `{"metadata": {"annotations": {"other": "y"}}}`,
+		},
+		{
+			name: "from takes matched segments",
+			ps:   PatchSet{{{Op: OpMove, From: "/a/*/old", Path: "/a/*/new"}}},
+			in:   `{"a": [{"old": 1}, {"old": 2}]}`,
+			want: `{"a": [{"new": 1}, {"new": 2}]}`,
+		},
+		{
+			name: "failed test discards only its group",
+			ps:   PatchSet{removeDefault("/a/*/v", 1.0)},
+			in:   `{"a": [{"v": 1}, {"v": 2}]}`,
+			want: `{"a": [{}, {"v": 2}]}`,
+		},
+	}
+	for _, test := range tests {
+		out, err := test.ps.Apply([]byte(test.in))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		same, err := EqualJSON(out, []byte(test.want))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err.Error())
+			continue
+		}
+		if !same {
+			t.Errorf("%s: got %s, want %s", test.name, out, test.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"os"
 	"strconv"
 )
@@ -11,20 +13,68 @@ var (
 	int32Zero = int32(0)
 )
 
+// JSON2YAML converts JSON to YAML with conventional key order, see OrderedJSON2YAML
 func JSON2YAML(buf []byte) (out []byte, err error) {
+	return OrderedJSON2YAML(buf, schema.GroupVersionKind{})
+}
+
+func YAML2JSON(buf []byte) (out []byte, err error) {
+	var m map[string]interface{}
+	if err = yaml.Unmarshal(buf, &m); err != nil {
+		return
+	}
+	out, err = json.Marshal(m)
+	return
+}
+
+// ApplyJSON fills type meta, namespace and name of a sanitized object, which are required by server side apply,
+// namespace is left out for cluster scoped objects if empty, a stale resourceVersion of a local file is dropped
+func ApplyJSON(buf []byte, apiVersion, kind, namespace, name string) (out []byte, err error) {
 	var m map[string]interface{}
 	if err = json.Unmarshal(buf, &m); err != nil {
 		return
 	}
-	out, err = yaml.Marshal(m)
+	metadata, _ := m["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	metadata["name"] = name
+	delete(metadata, "resourceVersion")
+	m["metadata"] = metadata
+	m["apiVersion"] = apiVersion
+	m["kind"] = kind
+	out, err = json.Marshal(m)
 	return
 }
 
-func YAML2JSON(buf []byte) (out []byte, err error) {
+// ZeroReplicasJSON sets '/spec/replicas' of an object to 0, an empty buf results in an object of only that
+func ZeroReplicasJSON(buf []byte) (out []byte, err error) {
+	m := map[string]interface{}{}
+	if len(buf) > 0 {
+		if err = json.Unmarshal(buf, &m); err != nil {
+			return
+		}
+	}
+	spec, _ := m["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+		m["spec"] = spec
+	}
+	spec["replicas"] = 0
+	out, err = json.Marshal(m)
+	return
+}
+
+// SetTypeMetaJSON sets apiVersion and kind of an object
+func SetTypeMetaJSON(buf []byte, gvk schema.GroupVersionKind) (out []byte, err error) {
 	var m map[string]interface{}
-	if err = yaml.Unmarshal(buf, &m); err != nil {
+	if err = json.Unmarshal(buf, &m); err != nil {
 		return
 	}
+	m["apiVersion"], m["kind"] = gvk.ToAPIVersionAndKind()
 	out, err = json.Marshal(m)
 	return
 }
@@ -38,3 +88,13 @@ func IsEnvZeroReplicas() bool {
 	v, _ := strconv.ParseBool(os.Getenv("KOOP_ZERO_REPLICAS"))
 	return v
 }
+
+// isOwnedBy checks whether an object is controlled by an owner of kind
+func isOwnedBy(refs []metav1.OwnerReference, kind string) bool {
+	for _, ref := range refs {
+		if ref.Kind == kind && ref.Controller != nil && *ref.Controller {
+			return true
+		}
+	}
+	return false
+}